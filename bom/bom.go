@@ -0,0 +1,267 @@
+// Package bom reads Apple Bill of Materials (.bom) files, which list every
+// path a package installs, without relying on the lsbom tool so it can run
+// and be tested on any platform.
+//
+// A BOM file is a BOMStore: a header pointing at a table of blocks and at a
+// list of named variables. The "Paths" variable holds a B+ tree whose leaves
+// pair a BOMPathInfo1 block, pointing at the entry's metadata, with a BOMFile
+// block holding the entry's name and the ID of its parent. All integers are
+// stored big-endian.
+package bom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+)
+
+// Magic string opening every BOM file
+const magic = "BOMStore"
+
+// Size of the fixed header before the block data
+const headerSize = 512
+
+// Errors returned while reading a BOM
+var (
+	ErrNotBOM    = errors.New("bom: not a BOM file")
+	ErrMalformed = errors.New("bom: malformed BOM file")
+)
+
+// Type is the kind of filesystem entry listed in a BOM
+type Type uint8
+
+// Entry types stored in BOMPathInfo2
+const (
+	TypeFile   Type = 1
+	TypeDir    Type = 2
+	TypeLink   Type = 3
+	TypeDevice Type = 4
+)
+
+// Entry is a single path listed in the BOM
+type Entry struct {
+	Path     string // path relative to the install prefix, without the leading "./"
+	Type     Type   // kind of entry
+	Mode     uint16 // permission and type bits
+	Size     uint32 // size of files in bytes
+	LinkName string // target of symlinks
+}
+
+// Location of a block in the file
+type pointer struct {
+	address uint32
+	length  uint32
+}
+
+// bomStore holds the decoded block table and variables of a BOM
+type bomStore struct {
+	data   []byte
+	blocks []pointer
+	vars   map[string]uint32
+}
+
+// ReadFile reads and parses the BOM at path
+func ReadFile(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse returns every entry of the "Paths" tree in the order it is stored,
+// which lists every directory before its contents
+func Parse(data []byte) ([]Entry, error) {
+	store, err := open(data)
+	if err != nil {
+		return nil, err
+	}
+
+	treeIndex, ok := store.vars["Paths"]
+	if !ok {
+		return nil, fmt.Errorf("%w: no Paths variable", ErrMalformed)
+	}
+	tree, err := store.block(treeIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(tree) < 21 || string(tree[:4]) != "tree" {
+		return nil, fmt.Errorf("%w: Paths is not a tree", ErrMalformed)
+	}
+	return store.paths(be32(tree[8:]))
+}
+
+// Decodes the header, block table and variables
+func open(data []byte) (*bomStore, error) {
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return nil, ErrNotBOM
+	}
+	store := &bomStore{data: data, vars: map[string]uint32{}}
+
+	indexOffset, indexLength := be32(data[16:]), be32(data[20:])
+	varsOffset, varsLength := be32(data[24:]), be32(data[28:])
+	index, err := store.slice(indexOffset, indexLength)
+	if err != nil || len(index) < 4 {
+		return nil, fmt.Errorf("%w: bad block table", ErrMalformed)
+	}
+	count := be32(index)
+	if uint64(count)*8 > uint64(len(index)-4) {
+		return nil, fmt.Errorf("%w: block table overflows", ErrMalformed)
+	}
+	for i := range count {
+		entry := index[4+i*8:]
+		store.blocks = append(store.blocks, pointer{address: be32(entry), length: be32(entry[4:])})
+	}
+
+	vars, err := store.slice(varsOffset, varsLength)
+	if err != nil || len(vars) < 4 {
+		return nil, fmt.Errorf("%w: bad variables", ErrMalformed)
+	}
+	numVars := be32(vars)
+	rest := vars[4:]
+	for range numVars {
+		if len(rest) < 5 || len(rest) < 5+int(rest[4]) {
+			return nil, fmt.Errorf("%w: variable overflows", ErrMalformed)
+		}
+		nameLength := int(rest[4])
+		store.vars[string(rest[5:5+nameLength])] = be32(rest)
+		rest = rest[5+nameLength:]
+	}
+	return store, nil
+}
+
+// Walks the leaves of the tree starting at the BOMPaths block and
+// resolves every entry's full path from its parent IDs
+func (s *bomStore) paths(child uint32) ([]Entry, error) {
+	type file struct {
+		parent uint32
+		name   string
+	}
+	files := map[uint32]file{}
+	var ids []uint32
+	var entries []Entry
+
+	node, err := s.block(child)
+	if err != nil {
+		return nil, err
+	}
+	// Descend along the leftmost children down to the first leaf
+	for depth := 0; ; depth++ {
+		if len(node) < 12 || depth > len(s.blocks) {
+			return nil, fmt.Errorf("%w: bad paths node", ErrMalformed)
+		}
+		if be16(node) != 0 {
+			break
+		}
+		if be16(node[2:]) == 0 {
+			return nil, nil
+		}
+		if node, err = s.block(be32(node[12:])); err != nil {
+			return nil, err
+		}
+	}
+
+	for visited := 0; ; visited++ {
+		if visited > len(s.blocks) {
+			return nil, fmt.Errorf("%w: cycle in paths leaves", ErrMalformed)
+		}
+		count := int(be16(node[2:]))
+		if len(node) < 12+count*8 {
+			return nil, fmt.Errorf("%w: paths leaf overflows", ErrMalformed)
+		}
+
+		for i := range count {
+			pair := node[12+i*8:]
+			info1, err := s.block(be32(pair))
+			if err != nil || len(info1) < 8 {
+				return nil, fmt.Errorf("%w: bad path info", ErrMalformed)
+			}
+			fileBlock, err := s.block(be32(pair[4:]))
+			if err != nil || len(fileBlock) < 4 {
+				return nil, fmt.Errorf("%w: bad file", ErrMalformed)
+			}
+
+			id := be32(info1)
+			name, _, _ := bytes.Cut(fileBlock[4:], []byte{0})
+			files[id] = file{parent: be32(fileBlock), name: string(name)}
+			ids = append(ids, id)
+
+			entry, err := s.pathInfo(be32(info1[4:]))
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+
+		forward := be32(node[4:])
+		if forward == 0 {
+			break
+		}
+		if node, err = s.block(forward); err != nil || len(node) < 12 {
+			return nil, fmt.Errorf("%w: bad paths leaf", ErrMalformed)
+		}
+	}
+
+	// Resolve full paths once every name is known
+	for i, id := range ids {
+		var parts []string
+		for current, hops := id, 0; current != 0; hops++ {
+			f, ok := files[current]
+			if !ok || hops > len(files) {
+				return nil, fmt.Errorf("%w: bad parent of %d", ErrMalformed, id)
+			}
+			parts = append(parts, f.name)
+			current = f.parent
+		}
+		for l, r := 0, len(parts)-1; l < r; l, r = l+1, r-1 {
+			parts[l], parts[r] = parts[r], parts[l]
+		}
+		entries[i].Path = path.Clean(path.Join(parts...))
+	}
+	return entries, nil
+}
+
+// Decodes the BOMPathInfo2 block holding an entry's metadata
+func (s *bomStore) pathInfo(index uint32) (Entry, error) {
+	info, err := s.block(index)
+	if err != nil || len(info) < 31 {
+		return Entry{}, fmt.Errorf("%w: bad path info", ErrMalformed)
+	}
+	entry := Entry{
+		Type: Type(info[0]),
+		Mode: be16(info[4:]),
+		Size: be32(info[18:]),
+	}
+	if entry.Type == TypeLink && len(info) >= 35 {
+		linkLength := be32(info[27:])
+		if link := info[31:]; uint64(linkLength) <= uint64(len(link)) {
+			name, _, _ := bytes.Cut(link[:linkLength], []byte{0})
+			entry.LinkName = string(name)
+		}
+	}
+	return entry, nil
+}
+
+// Returns the data of the block at index
+func (s *bomStore) block(index uint32) ([]byte, error) {
+	if index == 0 || int(index) >= len(s.blocks) {
+		return nil, fmt.Errorf("%w: block %d out of range", ErrMalformed, index)
+	}
+	ptr := s.blocks[index]
+	return s.slice(ptr.address, ptr.length)
+}
+
+// Returns length bytes at offset, failing if they run past the end of the file
+func (s *bomStore) slice(offset, length uint32) ([]byte, error) {
+	end := uint64(offset) + uint64(length)
+	if end > uint64(len(s.data)) {
+		return nil, fmt.Errorf("%w: range %d+%d past end of file", ErrMalformed, offset, length)
+	}
+	return s.data[offset:end], nil
+}
+
+func be16(b []byte) uint16 { return binary.BigEndian.Uint16(b) }
+func be32(b []byte) uint32 { return binary.BigEndian.Uint32(b) }
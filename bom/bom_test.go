@@ -0,0 +1,149 @@
+package bom
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// Builds BOM files block by block. Block 0 is the null block
+type builder struct {
+	blocks [][]byte
+}
+
+// Appends a block and returns its index
+func (b *builder) add(parts ...any) uint32 {
+	var data []byte
+	for _, part := range parts {
+		switch v := part.(type) {
+		case uint8:
+			data = append(data, v)
+		case uint16:
+			data = binary.BigEndian.AppendUint16(data, v)
+		case uint32:
+			data = binary.BigEndian.AppendUint32(data, v)
+		case string:
+			data = append(data, v...)
+		}
+	}
+	b.blocks = append(b.blocks, data)
+	return uint32(len(b.blocks))
+}
+
+// Adds a path entry and returns the BOMPathInfo1 and BOMFile block indices
+func (b *builder) entry(id, parent uint32, name string, kind Type, size uint32, link string) (uint32, uint32) {
+	info2 := b.add(uint8(kind), uint8(1), uint16(0), uint16(0o755), uint32(0), uint32(0), uint32(0), size, uint8(0), uint32(0), uint32(len(link)+1), link, uint8(0))
+	info1 := b.add(id, info2)
+	file := b.add(parent, name, uint8(0))
+	return info1, file
+}
+
+// Lays out the header, blocks, block table and variables
+func (b *builder) bytes(vars map[string]uint32) []byte {
+	data := make([]byte, headerSize)
+	copy(data, magic)
+
+	pointers := []pointer{{}}
+	for _, block := range b.blocks {
+		pointers = append(pointers, pointer{address: uint32(len(data)), length: uint32(len(block))})
+		data = append(data, block...)
+	}
+
+	indexOffset := len(data)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(pointers)))
+	for _, ptr := range pointers {
+		data = binary.BigEndian.AppendUint32(data, ptr.address)
+		data = binary.BigEndian.AppendUint32(data, ptr.length)
+	}
+	indexLength := len(data) - indexOffset
+
+	varsOffset := len(data)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(vars)))
+	for name, index := range vars {
+		data = binary.BigEndian.AppendUint32(data, index)
+		data = append(data, uint8(len(name)))
+		data = append(data, name...)
+	}
+
+	binary.BigEndian.PutUint32(data[8:], 1)
+	binary.BigEndian.PutUint32(data[12:], uint32(len(pointers)))
+	binary.BigEndian.PutUint32(data[16:], uint32(indexOffset))
+	binary.BigEndian.PutUint32(data[20:], uint32(indexLength))
+	binary.BigEndian.PutUint32(data[24:], uint32(varsOffset))
+	binary.BigEndian.PutUint32(data[28:], uint32(len(data)-varsOffset))
+	return data
+}
+
+// Builds a BOM for a package installing an app and a command line tool,
+// with the paths split over two leaves below a branch node
+func testBOM() []byte {
+	b := &builder{}
+	type pair struct{ info1, file uint32 }
+	var pairs []pair
+	add := func(id, parent uint32, name string, kind Type, size uint32, link string) {
+		info1, file := b.entry(id, parent, name, kind, size, link)
+		pairs = append(pairs, pair{info1, file})
+	}
+	add(1, 0, ".", TypeDir, 0, "")
+	add(2, 1, "Applications", TypeDir, 0, "")
+	add(3, 2, "Foo.app", TypeDir, 0, "")
+	add(4, 1, "usr", TypeDir, 0, "")
+	add(5, 4, "local", TypeDir, 0, "")
+	add(6, 5, "bin", TypeDir, 0, "")
+	add(7, 6, "foo", TypeFile, 1234, "")
+	add(8, 6, "foo-link", TypeLink, 0, "foo")
+
+	leaf := func(entries []pair, forward uint32) uint32 {
+		parts := []any{uint16(1), uint16(len(entries)), forward, uint32(0)}
+		for _, p := range entries {
+			parts = append(parts, p.info1, p.file)
+		}
+		return b.add(parts...)
+	}
+	second := leaf(pairs[4:], 0)
+	first := leaf(pairs[:4], second)
+	branch := b.add(uint16(0), uint16(1), uint32(0), uint32(0), first, uint32(0))
+	tree := b.add("tree", uint32(1), branch, uint32(4096), uint32(len(pairs)), uint8(0))
+
+	return b.bytes(map[string]uint32{"Paths": tree})
+}
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(testBOM())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Entry{
+		{Path: ".", Type: TypeDir},
+		{Path: "Applications", Type: TypeDir},
+		{Path: "Applications/Foo.app", Type: TypeDir},
+		{Path: "usr", Type: TypeDir},
+		{Path: "usr/local", Type: TypeDir},
+		{Path: "usr/local/bin", Type: TypeDir},
+		{Path: "usr/local/bin/foo", Type: TypeFile, Size: 1234},
+		{Path: "usr/local/bin/foo-link", Type: TypeLink, LinkName: "foo"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), entries)
+	}
+	for i, entry := range entries {
+		if entry.Path != want[i].Path || entry.Type != want[i].Type || entry.Size != want[i].Size || entry.LinkName != want[i].LinkName {
+			t.Errorf("Entry %d = %+v, want %+v", i, entry, want[i])
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse([]byte("not a bom")); !errors.Is(err, ErrNotBOM) {
+		t.Errorf("Expected ErrNotBOM, got %v", err)
+	}
+
+	// Truncated files must fail cleanly rather than panic
+	data := testBOM()
+	for length := headerSize; length < len(data); length++ {
+		if _, err := Parse(data[:length]); err == nil {
+			t.Fatalf("Expected error for file truncated to %d bytes", length)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/prompt"
+	"github.com/alewtschuk/rmapp/resolver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listFootprint bool
+	listLogical   bool
+	listSort      string
+	listFilter    string
+	listOutput    string
+)
+
+// listCmd prints an inventory of the installed apps
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists installed apps with their metadata and sizes",
+	Long: `Lists every app bundle found in the Applications folders with its name,
+bundle ID, version, location and bundle size.
+
+With '--footprint' the size of each app's associated files is added as well,
+which searches the whole Library once per app and takes considerably longer.`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// Keeps stdout for the JSON inventory, moving messages to stderr
+		if listOutput == "json" {
+			prompt.Output = os.Stderr
+		}
+		if !slices.Contains([]string{"name", "id", "size", "footprint"}, listSort) {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Invalid '--sort'. Please choose one of name, id, size or footprint...", 9))
+			os.Exit(1)
+		}
+		if listSort == "footprint" && !listFootprint {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] '--sort footprint' requires '--footprint'. Please run again with '--footprint' enabled...", 9))
+			os.Exit(1)
+		}
+		if listOutput != "table" && listOutput != "json" {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Invalid '--output'. Please choose one of table or json...", 9))
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := matchOptions()
+		opts.Logical = listLogical
+		setLogging(opts.Verbosity)
+
+		apps := filterApps(resolver.ListApps(listFootprint, opts), listFilter)
+		sortApps(apps, listSort)
+
+		if listOutput == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(apps); err != nil {
+				fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Error: "+err.Error(), 9))
+				os.Exit(1)
+			}
+			return
+		}
+
+		if len(apps) == 0 {
+			fmt.Fprintln(prompt.Output, "[rmapp] No apps found")
+			return
+		}
+		printApps(apps, listFootprint)
+	},
+}
+
+// Keeps the apps whose name or bundle ID contains filter, ignoring case
+func filterApps(apps []resolver.App, filter string) []resolver.App {
+	if filter == "" {
+		return apps
+	}
+	filter = strings.ToLower(filter)
+	var filtered []resolver.App
+	for _, app := range apps {
+		if strings.Contains(strings.ToLower(app.Name), filter) || strings.Contains(strings.ToLower(app.BundleID), filter) {
+			filtered = append(filtered, app)
+		}
+	}
+	return filtered
+}
+
+// Sorts apps by name or bundle ID ascending, or by size or footprint descending
+func sortApps(apps []resolver.App, by string) {
+	sort.SliceStable(apps, func(i, j int) bool {
+		switch by {
+		case "id":
+			return strings.ToLower(apps[i].BundleID) < strings.ToLower(apps[j].BundleID)
+		case "size":
+			return apps[i].Size > apps[j].Size
+		case "footprint":
+			return apps[i].Footprint > apps[j].Footprint
+		default:
+			return strings.ToLower(apps[i].Name) < strings.ToLower(apps[j].Name)
+		}
+	})
+}
+
+// Prints the apps as a table followed by the totals
+func printApps(apps []resolver.App, footprint bool) {
+	headers := []string{"NAME", "BUNDLE ID", "VERSION", "SIZE"}
+	if footprint {
+		headers = append(headers, "FOOTPRINT")
+	}
+	headers = append(headers, "LOCATION")
+
+	var rows [][]string
+	var totalSize, totalFootprint int64
+	for _, app := range apps {
+		totalSize += app.Size
+		totalFootprint += app.Footprint
+
+		row := []string{pfmt.ApplyColor(app.Name, 2), app.BundleID, app.Version, finder.FormatSize(app.Size)}
+		if footprint {
+			row = append(row, finder.FormatSize(app.Footprint))
+		}
+		rows = append(rows, append(row, pfmt.ApplyColor(app.Path, 3)))
+	}
+	printTable(headers, rows)
+
+	fmt.Printf("\n→ %s apps using %s", pfmt.ApplyColor(fmt.Sprintf("%d", len(apps)), 3), finder.FormatSize(totalSize))
+	if footprint {
+		fmt.Printf(", %s including associated files", finder.FormatSize(totalFootprint))
+	}
+	fmt.Println()
+}
+
+func init() {
+	listCmd.Flags().BoolVar(&listFootprint, "footprint", false, "Include the size of each app's associated files")
+	listCmd.Flags().BoolVarP(&listLogical, "logical", "l", false, "Show logical file size")
+	listCmd.Flags().StringVar(&listSort, "sort", "name", "Sort by name, id, size or footprint")
+	listCmd.Flags().StringVar(&listFilter, "filter", "", "Only list apps whose name or bundle ID contains this text")
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format, table or json")
+	rootCmd.AddCommand(listCmd)
+}
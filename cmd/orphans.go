@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/prompt"
+	"github.com/alewtschuk/rmapp/resolver"
+	"github.com/spf13/cobra"
+)
+
+var orphansLogical bool
+
+// orphansCmd lists data left behind by apps that are no longer installed
+var orphansCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "Lists data left behind by apps that are no longer installed",
+	Long: `Indexes the bundle IDs of every installed app and lists the bundle ID named
+preferences, caches, containers and other data in ~/Library and /Library that
+no installed app owns, grouped by bundle ID with sizes.
+
+Remove an orphan's data with: rmapp --bundle-id <bundle_id>`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := matchOptions()
+		opts.Logical = orphansLogical
+		setLogging(opts.Verbosity)
+
+		orphans := resolver.FindOrphans(opts)
+		if len(orphans) == 0 {
+			fmt.Fprintln(prompt.Output, "[rmapp] No orphaned data found")
+			return
+		}
+
+		var totalSize int64
+		var numFiles int
+		fmt.Printf("\nFound orphaned data of %s apps\n", pfmt.ApplyColor(fmt.Sprintf("%d", len(orphans)), 3))
+		for _, orphan := range orphans {
+			size := orphan.Size(opts.Logical)
+			totalSize += size
+			numFiles += len(orphan.Matches)
+
+			fmt.Printf("\n• %s %s\n", pfmt.ApplyColor(orphan.BundleID, 2), finder.FormatSize(size))
+			for _, match := range orphan.Matches {
+				fmt.Printf("    %s (%s) %s\n", pfmt.ApplyColor(match.Path, 3), match.Category, finder.FormatSize(match.Size(opts.Logical)))
+			}
+		}
+
+		fmt.Printf("\n→ Total: %s in %d files\n\n", finder.FormatSize(totalSize), numFiles)
+		fmt.Println("Run rmapp --bundle-id <bundle_id> to remove an app's orphaned data")
+	},
+}
+
+func init() {
+	orphansCmd.Flags().BoolVarP(&orphansLogical, "logical", "l", false, "Show logical file size")
+	rootCmd.AddCommand(orphansCmd)
+}
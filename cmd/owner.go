@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/resolver"
+	"github.com/spf13/cobra"
+)
+
+// ownerCmd finds the installed apps a file likely belongs to
+var ownerCmd = &cobra.Command{
+	Use:   "owner <path>",
+	Short: "Shows which installed app a file likely belongs to",
+	Long: `Checks the bundle ID, embedded helper and extension identifiers and the name
+of every installed app against the path and lists the likely owners by
+confidence. Use it to find out which app to remove for a mystery file such as
+a launch daemon in /Library/LaunchDaemons.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := matchOptions()
+		setLogging(opts.Verbosity)
+
+		owners := resolver.FindOwners(args[0], opts)
+		if len(owners) == 0 {
+			fmt.Printf("[rmapp] No installed app matches %s\n", pfmt.ApplyColor(args[0], 3))
+			os.Exit(1)
+		}
+
+		fmt.Printf("[rmapp] Likely owners of %s:\n", pfmt.ApplyColor(args[0], 3))
+		for _, owner := range owners {
+			printOwner(owner)
+		}
+	},
+}
+
+// Prints an owner with the rule and component that attributed the path to it
+func printOwner(owner finder.Owner) {
+	match := owner.Match
+	fmt.Printf("• %s", pfmt.ApplyColor(owner.Target.AppName, 2))
+	if owner.Target.BundleID != "" {
+		fmt.Printf(" (%s)", owner.Target.BundleID)
+	}
+	fmt.Printf(" %s\n", finder.FormatConfidence(match.Confidence))
+	fmt.Printf("    installed at %s\n", owner.Target.BundlePath)
+	fmt.Printf("    %s %q on %s\n", match.Rule, match.Identifier.Value, pfmt.ApplyColor(match.Path, 3))
+}
+
+func init() {
+	rootCmd.AddCommand(ownerCmd)
+}
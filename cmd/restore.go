@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/deleter"
+	"github.com/alewtschuk/rmapp/prompt"
+	"github.com/alewtschuk/rmapp/trash"
+	"github.com/spf13/cobra"
+)
+
+var restoreList bool
+
+// restoreCmd moves the files of a previous run back out of the Trash
+var restoreCmd = &cobra.Command{
+	Use:   "restore [run_id | app_name | bundle_id]",
+	Short: "Moves files rmapp put in the Trash back to where they were",
+	Long: `Every run that moves files to the Trash records where each file came from
+and where it ended up. Restore moves all files of the newest run matching the
+run ID, app name or bundle ID back, or of the newest run when none is given.
+
+Files whose original location has been reoccupied are left in the Trash and
+reported, so they can be restored again after moving the new file away.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setLogging(isVerbose)
+
+		if restoreList {
+			listRuns()
+			return
+		}
+
+		query := ""
+		if len(args) > 0 {
+			query = args[0]
+		}
+		manifest, err := deleter.FindManifest(query)
+		if err != nil {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Error: "+err.Error(), 9))
+			os.Exit(1)
+		}
+
+		bin, err := trash.ByName(manifest.Backend)
+		if err != nil {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Error: "+err.Error(), 9))
+			os.Exit(1)
+		}
+
+		var restored, conflicts int
+		results := manifest.Restore(bin)
+		for _, result := range results {
+			switch {
+			case result.Err == nil:
+				restored++
+				fmt.Fprintf(prompt.Output, "• Restored %s\n", pfmt.ApplyColor(result.Item.Original, 3))
+			case errors.Is(result.Err, deleter.ErrConflict):
+				conflicts++
+				fmt.Fprintf(prompt.Output, "• %s %s: %v, still in the Trash at %s\n", pfmt.ApplyColor("Conflict", 9), pfmt.ApplyColor(result.Item.Original, 3), result.Err, result.Item.Trashed)
+			default:
+				fmt.Fprintf(prompt.Output, "• %s %s: %v\n", pfmt.ApplyColor("Skipped", 3), pfmt.ApplyColor(result.Item.Original, 3), result.Err)
+			}
+		}
+		if err := manifest.Save(); err != nil {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] WARN: could not update run "+manifest.Run+": "+err.Error(), 3))
+		}
+
+		fmt.Fprintf(prompt.Output, "\n→ Restored %d of %d files from run %s\n", restored, len(results), manifest.Run)
+		if conflicts > 0 {
+			fmt.Fprintf(prompt.Output, "→ %s conflicts left in the Trash. Move the new files away and run again with: rmapp restore %s\n", pfmt.ApplyColor(fmt.Sprintf("%d", conflicts), 9), manifest.Run)
+		}
+	},
+}
+
+// Prints the recorded runs that can be restored
+func listRuns() {
+	manifests, err := deleter.Manifests()
+	if err != nil || len(manifests) == 0 {
+		fmt.Fprintln(prompt.Output, "[rmapp] No runs to restore")
+		return
+	}
+
+	var rows [][]string
+	for _, manifest := range manifests {
+		rows = append(rows, []string{
+			pfmt.ApplyColor(manifest.Run, 2),
+			manifest.App,
+			manifest.BundleID,
+			manifest.Created.Format("2006-01-02 15:04"),
+			fmt.Sprintf("%d", len(manifest.Items)),
+		})
+	}
+	printTable([]string{"RUN", "APP", "BUNDLE ID", "TRASHED", "FILES"}, rows)
+}
+
+func init() {
+	restoreCmd.Flags().BoolVar(&restoreList, "list", false, "List the runs that can be restored")
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -10,8 +10,14 @@ import (
 	"os"
 
 	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/deleter"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/index"
 	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/output"
+	"github.com/alewtschuk/rmapp/prompt"
 	"github.com/alewtschuk/rmapp/resolver"
+	"github.com/alewtschuk/rmapp/trash"
 	"github.com/spf13/cobra"
 )
 
@@ -34,18 +40,45 @@ var (
 	versionOpt   bool
 	isSize       bool
 	isBundleOnly bool
+	bundleIDOpt  string
+	appDepth     int
+	minConf      float64
+	noCache      bool
+	isKill       bool
+	trashOpt     string
+	platformOpt  string
+	rootOpt      string
+	homeOpt      string
+	allowDelete  bool
+	outputOpt    string
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "rmapp app_name",
-	Short: "Removes specified macOS apps and thier associated files",
+	Use:   "rmapp <app_name | bundle_id | path>",
+	Short: "Removes specified macOS and Linux apps and thier associated files",
 	Long: banner + `        
 
 rmapp is a macOS app removal tool for command line and power users.
 It deletes both standard .app bundles and associated files stored elsewhere
-in your system, securely, with file size reporting, and default safe trashing.`,
-	Args: cobra.MinimumNArgs(1), // Set minimum required args to 1 (app to remove)
+in your system, securely, with file size reporting, and default safe trashing.
+
+The target may be an app name (Slack), a bundle ID (com.tinyspeck.slackmacgap),
+or a path to an .app, a file inside one, or a symlink into one (/usr/local/bin/code).
+
+On Linux the app's desktop entry stands in for the bundle, and its data is
+searched for in the XDG base directories, autostart and Flatpak's ~/.var/app.`,
+	// Keeps directory listings and sizes read during the run for the next one
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		index.SaveDefault()
+	},
+	Args: func(cmd *cobra.Command, args []string) error {
+		// The app name may be omitted when searching for leftovers by bundle ID
+		if bundleIDOpt != "" {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args) // Set minimum required args to 1 (app to remove)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Check if we're dealing with multiple arguments that might be an unquoted app name
 		if len(args) > 1 {
@@ -63,19 +96,23 @@ in your system, securely, with file size reporting, and default safe trashing.`,
 
 			// Only suggest combining if we found actual app name parts
 			if len(appNameParts) > 1 {
-				fmt.Println("[rmapp] ⚠️ Detected multiple app name arguments. Did you forget to wrap the app name in quotes?")
-				fmt.Printf("           Try: rmapp \"%s\"", joinWithSpaces(appNameParts))
+				fmt.Fprintln(prompt.Output, "[rmapp] ⚠️ Detected multiple app name arguments. Did you forget to wrap the app name in quotes?")
+				fmt.Fprintf(prompt.Output, "           Try: rmapp \"%s\"", joinWithSpaces(appNameParts))
 
 				// Add any flags back to the suggestion
 				for _, flag := range flags {
-					fmt.Printf(" %s", flag)
+					fmt.Fprintf(prompt.Output, " %s", flag)
 				}
-				fmt.Println()
+				fmt.Fprintln(prompt.Output)
 				os.Exit(0)
 			}
 		}
 
-		appName := args[0]
+		// Without a name the bundle ID doubles as the name to search for
+		appName := bundleIDOpt
+		if len(args) > 0 {
+			appName = args[0]
+		}
 		var opts options.Options
 		// Enables logical file size if peek is used
 		switch {
@@ -111,17 +148,41 @@ in your system, securely, with file size reporting, and default safe trashing.`,
 			}
 
 		}
+		opts.BundleID = bundleIDOpt
+		opts.AppDepth = appDepth
+		opts.MinConfidence = minConf
+		opts.NoCache = noCache
+		opts.Kill = isKill
+		opts.Trash = trashOpt
+		opts.Platform = platformOpt
+		opts.Root = rootOpt
+		opts.Home = homeOpt
+		opts.AllowDelete = allowDelete
+
+		if outputOpt != output.TEXT {
+			opts.Output = outputOpt
+		}
 
-		if !opts.Verbosity {
-			log.SetOutput(io.Discard)
-		} else {
-			log.SetOutput(os.Stdout)
-			log.SetFlags(0)
+		// A rebased system is only inspected unless removing from it is explicitly allowed
+		if opts.Root != "" && !opts.AllowDelete && !opts.Size {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Removal is disabled under '--root'. Showing matches instead, pass '--allow-delete' to remove them", 3))
+			opts.Peek = true
 		}
+
+		setLogging(opts.Verbosity)
+		// Undo or finish removals a previous run was interrupted in
+		deleter.Recover()
+
 		// Create and populate new resolver
 		instance := resolver.NewResolver(appName, opts)
+		if opts.Output != "" {
+			if err := instance.WriteReport(os.Stdout); err != nil {
+				os.Exit(1)
+			}
+			return
+		}
 		if instance.Reported {
-			os.Exit(0)
+			return
 		}
 
 		instance.Deleter.Delete()
@@ -166,12 +227,47 @@ func init() {
 			pfmt.ApplyColor("Trash (Default, Safe, RECOVERABLE)", 2),
 			pfmt.ApplyColor("Force (Full file removal, Unsafe, UNRECOVERABLE)", 9)),
 	)
-	rootCmd.Flags().BoolVarP(&isVerbose, "verbose", "v", false, "Show detailed output")
+	rootCmd.PersistentFlags().BoolVarP(&isVerbose, "verbose", "v", false, "Show detailed output")
 	rootCmd.Flags().BoolVarP(&isPeek, "peek", "p", false, "Peek matched files")
 	rootCmd.Flags().BoolVarP(&isLogical, "logical", "l", false, "Show logical file size")
 	rootCmd.Flags().BoolVar(&versionOpt, "version", false, "Show rmapp version")
 	rootCmd.Flags().BoolVarP(&isSize, "size", "s", false, "Show the total size of the application's data")
 	rootCmd.Flags().BoolVarP(&isBundleOnly, "bundle", "b", false, "Removes only the Bundle ID. Equivalent to dragging to trash")
+	rootCmd.Flags().BoolVar(&isKill, "kill", false, "Quit running app processes without asking, force quitting them if they do not exit")
+	rootCmd.Flags().StringVar(&trashOpt, "trash", "", fmt.Sprintf("Trash backend to move files to: %s or %s (default picked by platform)", trash.NATIVE, trash.FREEDESKTOP))
+	rootCmd.PersistentFlags().IntVar(&appDepth, "app-depth", finder.DISCOVERY_DEPTH, "How many folder levels below each Applications folder are searched for apps")
+	rootCmd.PersistentFlags().Float64Var(&minConf, "min-confidence", 0, "Ignore matches with a confidence score below this value (0 to 1)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Read everything from disk instead of the scan index")
+	rootCmd.PersistentFlags().StringVarP(&bundleIDOpt, "bundle-id", "i", "", "Search using this bundle ID. Use to clean leftovers of an app that is already gone")
+	rootCmd.PersistentFlags().StringVar(&rootOpt, "root", "", "Search the system mounted at this directory, such as a disk image or backup, instead of /")
+	rootCmd.PersistentFlags().StringVar(&homeOpt, "home", "", "Home directory to search as seen inside '--root' (default $HOME)")
+	rootCmd.Flags().BoolVar(&allowDelete, "allow-delete", false, "Allow removing the files found under '--root'")
+	rootCmd.Flags().StringVarP(&outputOpt, "output", "o", output.TEXT, fmt.Sprintf("Output format: %s, %s for a single document or %s to stream results", output.TEXT, output.JSON, output.NDJSON))
+	rootCmd.PersistentFlags().StringVar(&platformOpt, "platform", "", fmt.Sprintf("App and data layout to search: %s or %s (default is the running platform)", finder.DARWIN, finder.LINUX))
+}
+
+// Sends log output along with the other messages when verbose, otherwise discards it
+func setLogging(verbose bool) {
+	if !verbose {
+		log.SetOutput(io.Discard)
+		return
+	}
+	log.SetOutput(prompt.Output)
+	log.SetFlags(0)
+}
+
+// Builds the options shared by all commands that run the matcher
+func matchOptions() options.Options {
+	return options.Options{
+		Verbosity:     isVerbose,
+		BundleID:      bundleIDOpt,
+		AppDepth:      appDepth,
+		MinConfidence: minConf,
+		NoCache:       noCache,
+		Platform:      platformOpt,
+		Root:          rootOpt,
+		Home:          homeOpt,
+	}
 }
 
 // Prints version
@@ -184,52 +280,71 @@ func getVersion() {
 
 // Checks argument compatibility
 func checkArgs() {
+	if err := output.Valid(outputOpt); err != nil {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Invalid '--output'. "+err.Error(), 9))
+		os.Exit(1)
+	}
+	// Keeps stdout for the report, moving messages and prompts to stderr
+	if outputOpt != output.TEXT {
+		prompt.Output = os.Stderr
+	}
+
+	if err := finder.ValidPlatform(platformOpt); err != nil {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Invalid '--platform'. "+err.Error(), 9))
+		os.Exit(1)
+	}
+
 	if isPeek && isForce {
-		pfmt.Printcln("[rmapp] Incompatible args '--force' and '--peek'. Please choose one argument and run again...", 9)
-		fmt.Println()
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--force' and '--peek'. Please choose one argument and run again...", 9))
+		fmt.Fprintln(prompt.Output)
 		os.Exit(0)
 	}
 
 	if isSize && isForce {
-		pfmt.Printcln("[rmapp] Incompatible args '--force' and '--size'. Please choose one argument and run again...", 9)
-		fmt.Println()
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--force' and '--size'. Please choose one argument and run again...", 9))
+		fmt.Fprintln(prompt.Output)
 		os.Exit(0)
 	}
 
 	if isLogical && isForce {
-		pfmt.Printcln("[rmapp] Incompatible args '--force' and '--logical'. '--logical' can only be run in peek context. \nPlease run again with '--force' alone or '--logical' and '--peek'...", 9)
-		fmt.Println()
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--force' and '--logical'. '--logical' can only be run in peek context. \nPlease run again with '--force' alone or '--logical' and '--peek'...", 9))
+		fmt.Fprintln(prompt.Output)
 		os.Exit(0)
 	}
 
 	if !(isPeek || isSize) && isLogical {
-		pfmt.Printcln("[rmapp] Incompatible args '--logical' must be used in '--peek' context. Please run again with '--peek' enabled...", 9)
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--logical' must be used in '--peek' context. Please run again with '--peek' enabled...", 9))
 		os.Exit(0)
 	}
 
 	if isPeek && isSize {
-		pfmt.Printcln("[rmapp] Incompatible args '--size' is shown in '--peek'. Please choose one argument and run again...", 9)
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--size' is shown in '--peek'. Please choose one argument and run again...", 9))
 		os.Exit(0)
 	}
 
 	if isPeek && isBundleOnly {
-		pfmt.Printcln("[rmapp] Incompatible args '--peek' and '--bundle'. Please choose one argument and run again...", 9)
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--peek' and '--bundle'. Please choose one argument and run again...", 9))
 		os.Exit(0)
 	}
 
 	if isLogical && isBundleOnly {
-		pfmt.Printcln("[rmapp] Incompatible args '--logical' and '--bundle'. Please choose one argument and run again...", 9)
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--logical' and '--bundle'. Please choose one argument and run again...", 9))
 		os.Exit(0)
 	}
 
 	if isBundleOnly && isSize {
-		fmt.Println(pfmt.ApplyColor("[rmapp] Incompatible args '--size' and '--bundle'. Please choose one argument and run again...", 9))
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--size' and '--bundle'. Please choose one argument and run again...", 9))
+		os.Exit(0)
+	}
+
+	if minConf < 0 || minConf > 1 {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Invalid '--min-confidence'. Please choose a value between 0 and 1...", 9))
 		os.Exit(0)
 	}
 
 	//-v or --version
 	if versionOpt && (isForce || isPeek || isSize || isLogical || isVerbose) {
-		fmt.Println(pfmt.ApplyColor("[rmapp] Incompatible args '--version' cannot be used with other flags", 9))
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Incompatible args '--version' cannot be used with other flags", 9))
 		os.Exit(0)
 	}
 }
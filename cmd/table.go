@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/finder"
+)
+
+// Prints rows as left aligned columns, padding on the width without color codes
+func printTable(headers []string, rows [][]string) {
+	widths := make([]int, len(headers))
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(finder.StripColor(cell)))
+		}
+	}
+
+	printRow := func(row []string) {
+		var line strings.Builder
+		for i, cell := range row {
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-len(finder.StripColor(cell))+2))
+			}
+		}
+		fmt.Println(line.String())
+	}
+
+	printRow(headers)
+	for _, row := range rows {
+		printRow(row)
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/prompt"
+	"github.com/alewtschuk/rmapp/resolver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	topCount   int
+	topLogical bool
+)
+
+// topCmd ranks installed apps by the space removing them would free
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Ranks installed apps by total reclaimable space",
+	Long: `Walks the Applications folders and the Library once, attributes every matched
+file to the installed app it most likely belongs to and ranks the apps by the
+combined size of their bundle and associated files.`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if topCount < 1 {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Invalid '--count'. Please choose a number of apps above 0...", 9))
+			os.Exit(1)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := matchOptions()
+		opts.Logical = topLogical
+		setLogging(opts.Verbosity)
+
+		type ranked struct {
+			footprint    finder.Footprint
+			bundle, data int64
+		}
+		var apps []ranked
+		for _, footprint := range resolver.Footprints(opts) {
+			bundle, data := footprint.Sizes(opts.Logical)
+			apps = append(apps, ranked{footprint, bundle, data})
+		}
+		if len(apps) == 0 {
+			fmt.Fprintln(prompt.Output, "[rmapp] No apps found")
+			return
+		}
+		sort.SliceStable(apps, func(i, j int) bool {
+			return apps[i].bundle+apps[i].data > apps[j].bundle+apps[j].data
+		})
+
+		var total int64
+		for _, app := range apps {
+			total += app.bundle + app.data
+		}
+
+		var rows [][]string
+		for i, app := range apps[:min(topCount, len(apps))] {
+			rows = append(rows, []string{
+				fmt.Sprintf("%d", i+1),
+				pfmt.ApplyColor(app.footprint.Target.AppName, 2),
+				app.footprint.Target.BundleID,
+				finder.FormatSize(app.bundle),
+				finder.FormatSize(app.data),
+				finder.FormatSize(app.bundle + app.data),
+				fmt.Sprintf("%d", len(app.footprint.Matches)),
+			})
+		}
+		printTable([]string{"#", "NAME", "BUNDLE ID", "APP", "DATA", "TOTAL", "FILES"}, rows)
+
+		fmt.Printf("\n→ Total: %s reclaimable across %s apps\n", finder.FormatSize(total), pfmt.ApplyColor(fmt.Sprintf("%d", len(apps)), 3))
+	},
+}
+
+func init() {
+	topCmd.Flags().IntVarP(&topCount, "count", "n", 10, "Number of apps to show")
+	topCmd.Flags().BoolVarP(&topLogical, "logical", "l", false, "Show logical file size")
+	rootCmd.AddCommand(topCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/prompt"
+	"github.com/alewtschuk/rmapp/resolver"
+	"github.com/spf13/cobra"
+)
+
+// whyCmd explains why a path is or is not matched for an app
+var whyCmd = &cobra.Command{
+	Use:   "why <app_name | bundle_id | path> <file>",
+	Short: "Explains why a file is or is not matched for an app",
+	Long: `Replays the matcher for a single file and prints every decision taken from
+the search root down to it: which rule matched, which folders were skipped
+because of the depth limit or the vendor domain hint, or why no rule applied.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := matchOptions()
+		setLogging(opts.Verbosity)
+
+		target, err := resolver.ResolveTarget(args[0], opts)
+		if err != nil {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Error: "+err.Error(), 9))
+			os.Exit(1)
+		}
+
+		printExplanation(finder.Explain(target, args[1], opts), target)
+	},
+}
+
+// Prints the explanation as a list of traced decisions
+func printExplanation(exp finder.Explanation, target finder.Target) {
+	fmt.Printf("[rmapp] Explaining %s for %s", pfmt.ApplyColor(exp.Path, 3), pfmt.ApplyColor(target.AppName, 2))
+	if target.BundleID != "" {
+		fmt.Printf(" (%s)", pfmt.ApplyColor(target.BundleID, 2))
+	}
+	fmt.Println()
+	if exp.Root != "" {
+		fmt.Printf("Search root: %s (%s)\n", exp.Root, exp.Category)
+	}
+	fmt.Println()
+
+	for _, trace := range exp.Trace {
+		fmt.Printf("%s %s\n", traceMarker(trace.Outcome), trace.Path)
+		fmt.Printf("    %s: %s\n", trace.Outcome, trace.Detail)
+		for _, check := range trace.Checks {
+			fmt.Printf("      - %s\n", check)
+		}
+	}
+	fmt.Println()
+
+	if exp.Matched {
+		fmt.Printf("→ %s\n", pfmt.ApplyColor("Matched: the path would be removed", 2))
+	} else {
+		fmt.Printf("→ %s\n", pfmt.ApplyColor("Not matched: the path would be kept", 9))
+	}
+}
+
+// Returns a colored marker for a trace outcome
+func traceMarker(outcome string) string {
+	switch outcome {
+	case finder.OutcomeMatched:
+		return pfmt.ApplyColor("✓", 2)
+	case finder.OutcomeDescended:
+		return pfmt.ApplyColor("↓", 3)
+	case finder.OutcomeSkipped, finder.OutcomeDropped:
+		return pfmt.ApplyColor("↷", 3)
+	case finder.OutcomeMissing:
+		return pfmt.ApplyColor("?", 3)
+	default:
+		return pfmt.ApplyColor("✗", 9)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(whyCmd)
+}
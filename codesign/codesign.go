@@ -0,0 +1,218 @@
+// Package codesign reads the embedded code signature of Mach-O binaries to
+// recover the signing identifier, team ID and entitlements without relying
+// on the codesign tool, so it can run and be tested on any platform.
+package codesign
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/plist"
+)
+
+// Load command holding the offset of the code signature in __LINKEDIT
+const lcCodeSignature = 0x1d
+
+// Magic numbers of the signature blobs
+const (
+	magicEmbeddedSignature = 0xfade0cc0
+	magicCodeDirectory     = 0xfade0c02
+	magicEntitlements      = 0xfade7171
+)
+
+// Superblob slot types
+const (
+	slotCodeDirectory = 0
+	slotEntitlements  = 5
+)
+
+// First CodeDirectory version carrying a team ID
+const teamIDVersion = 0x20200
+
+// Entitlement keys carrying group identifiers
+const (
+	EntitlementAppGroups      = "com.apple.security.application-groups"
+	EntitlementKeychainGroups = "keychain-access-groups"
+)
+
+// ErrUnsigned is returned for binaries without an embedded code signature
+var ErrUnsigned = errors.New("codesign: binary has no code signature")
+
+// Signature holds the parts of a code signature rmapp uses to find app data
+type Signature struct {
+	Identifier     string         // signing identifier, usually the bundle ID
+	TeamID         string         // developer team identifier, empty for ad-hoc signatures
+	Entitlements   map[string]any // decoded entitlements plist
+	AppGroups      []string       // com.apple.security.application-groups
+	KeychainGroups []string       // keychain-access-groups
+}
+
+// ReadBundle reads the signature of the bundle's main executable
+func ReadBundle(bundlePath, executable string) (Signature, error) {
+	if executable == "" {
+		executable = strings.TrimSuffix(filepath.Base(bundlePath), filepath.Ext(bundlePath))
+	}
+	return ReadFile(filepath.Join(bundlePath, "Contents", "MacOS", executable))
+}
+
+// ReadFile reads the code signature of the Mach-O binary at path.
+//
+// Universal binaries return the signature of the first signed slice
+func ReadFile(path string) (Signature, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Signature{}, err
+	}
+	defer file.Close()
+
+	fat, err := macho.NewFatFile(file)
+	if err == nil {
+		defer fat.Close()
+		for _, arch := range fat.Arches {
+			sig, err := readSlice(file, arch.File, int64(arch.Offset))
+			if err == nil {
+				return sig, nil
+			}
+			if !errors.Is(err, ErrUnsigned) {
+				return Signature{}, err
+			}
+		}
+		return Signature{}, ErrUnsigned
+	}
+	if !errors.Is(err, macho.ErrNotFat) {
+		return Signature{}, err
+	}
+
+	thin, err := macho.NewFile(file)
+	if err != nil {
+		return Signature{}, err
+	}
+	defer thin.Close()
+	return readSlice(file, thin, 0)
+}
+
+// Locates LC_CODE_SIGNATURE in a single architecture slice and parses the
+// superblob it points at. Offsets are relative to the start of the slice
+func readSlice(r io.ReaderAt, f *macho.File, base int64) (Signature, error) {
+	for _, load := range f.Loads {
+		raw := load.Raw()
+		if len(raw) < 16 || f.ByteOrder.Uint32(raw[0:4]) != lcCodeSignature {
+			continue
+		}
+		dataOff := f.ByteOrder.Uint32(raw[8:12])
+		dataSize := f.ByteOrder.Uint32(raw[12:16])
+
+		blob := make([]byte, dataSize)
+		if _, err := r.ReadAt(blob, base+int64(dataOff)); err != nil {
+			return Signature{}, fmt.Errorf("codesign: reading signature: %w", err)
+		}
+		return Parse(blob)
+	}
+	return Signature{}, ErrUnsigned
+}
+
+// Parse decodes an embedded signature superblob
+func Parse(data []byte) (Signature, error) {
+	if len(data) < 12 || binary.BigEndian.Uint32(data[0:4]) != magicEmbeddedSignature {
+		return Signature{}, errors.New("codesign: invalid signature superblob")
+	}
+
+	var sig Signature
+	count := binary.BigEndian.Uint32(data[8:12])
+	if uint64(count) > uint64(len(data)-12)/8 {
+		return Signature{}, errors.New("codesign: invalid superblob index")
+	}
+
+	foundDirectory := false
+	for i := uint32(0); i < count; i++ {
+		entry := data[12+8*i:]
+		slot := binary.BigEndian.Uint32(entry[0:4])
+		blob, err := subBlob(data, binary.BigEndian.Uint32(entry[4:8]))
+		if err != nil {
+			return Signature{}, err
+		}
+
+		switch {
+		case slot == slotCodeDirectory && !foundDirectory:
+			if err := parseCodeDirectory(blob, &sig); err != nil {
+				return Signature{}, err
+			}
+			foundDirectory = true
+		case slot == slotEntitlements:
+			if err := parseEntitlements(blob, &sig); err != nil {
+				return Signature{}, err
+			}
+		}
+	}
+
+	if !foundDirectory {
+		return Signature{}, errors.New("codesign: signature has no code directory")
+	}
+	return sig, nil
+}
+
+// Returns the blob starting at offset, bounded by its declared length
+func subBlob(data []byte, offset uint32) ([]byte, error) {
+	if uint64(offset)+8 > uint64(len(data)) {
+		return nil, errors.New("codesign: blob offset out of range")
+	}
+	length := binary.BigEndian.Uint32(data[offset+4 : offset+8])
+	if length < 8 || uint64(offset)+uint64(length) > uint64(len(data)) {
+		return nil, errors.New("codesign: blob length out of range")
+	}
+	return data[offset : offset+length], nil
+}
+
+// Extracts the signing identifier and team ID from a CodeDirectory blob
+func parseCodeDirectory(blob []byte, sig *Signature) error {
+	if len(blob) < 44 || binary.BigEndian.Uint32(blob[0:4]) != magicCodeDirectory {
+		return errors.New("codesign: invalid code directory")
+	}
+	version := binary.BigEndian.Uint32(blob[8:12])
+
+	sig.Identifier = cString(blob, binary.BigEndian.Uint32(blob[20:24]))
+	if version >= teamIDVersion && len(blob) >= 52 {
+		if teamOffset := binary.BigEndian.Uint32(blob[48:52]); teamOffset != 0 {
+			sig.TeamID = cString(blob, teamOffset)
+		}
+	}
+	return nil
+}
+
+// Decodes the entitlements plist and pulls out the group identifiers
+func parseEntitlements(blob []byte, sig *Signature) error {
+	if binary.BigEndian.Uint32(blob[0:4]) != magicEntitlements {
+		return errors.New("codesign: invalid entitlements blob")
+	}
+	root, err := plist.Decode(blob[8:])
+	if err != nil {
+		return fmt.Errorf("codesign: entitlements: %w", err)
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return errors.New("codesign: entitlements are not a dictionary")
+	}
+
+	sig.Entitlements = dict
+	sig.AppGroups = plist.Strings(dict, EntitlementAppGroups)
+	sig.KeychainGroups = plist.Strings(dict, EntitlementKeychainGroups)
+	return nil
+}
+
+// Reads a NUL terminated string at offset
+func cString(blob []byte, offset uint32) string {
+	if uint64(offset) >= uint64(len(blob)) {
+		return ""
+	}
+	rest := blob[offset:]
+	if end := strings.IndexByte(string(rest), 0); end >= 0 {
+		rest = rest[:end]
+	}
+	return string(rest)
+}
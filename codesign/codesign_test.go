@@ -0,0 +1,168 @@
+package codesign
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testEntitlements = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>com.apple.security.app-sandbox</key><true/>
+<key>com.apple.security.application-groups</key>
+<array><string>ABCDE12345.com.vendor.shared</string><string>group.com.vendor.app</string></array>
+<key>keychain-access-groups</key>
+<array><string>ABCDE12345.com.vendor.app</string></array>
+</dict></plist>`
+
+// buildSuperblob assembles an embedded signature with a CodeDirectory and optional entitlements.
+func buildSuperblob(identifier, teamID, entitlements string) []byte {
+	cd := make([]byte, 52)
+	binary.BigEndian.PutUint32(cd[0:], magicCodeDirectory)
+	binary.BigEndian.PutUint32(cd[8:], teamIDVersion)
+	binary.BigEndian.PutUint32(cd[20:], uint32(len(cd)))
+	cd = append(cd, identifier+"\x00"...)
+	if teamID != "" {
+		binary.BigEndian.PutUint32(cd[48:], uint32(len(cd)))
+		cd = append(cd, teamID+"\x00"...)
+	}
+	binary.BigEndian.PutUint32(cd[4:], uint32(len(cd)))
+
+	blobs := [][]byte{cd}
+	slots := []uint32{slotCodeDirectory}
+	if entitlements != "" {
+		ent := make([]byte, 8, 8+len(entitlements))
+		binary.BigEndian.PutUint32(ent[0:], magicEntitlements)
+		binary.BigEndian.PutUint32(ent[4:], uint32(8+len(entitlements)))
+		blobs = append(blobs, append(ent, entitlements...))
+		slots = append(slots, slotEntitlements)
+	}
+
+	header := make([]byte, 12+8*len(blobs))
+	binary.BigEndian.PutUint32(header[0:], magicEmbeddedSignature)
+	binary.BigEndian.PutUint32(header[8:], uint32(len(blobs)))
+	offset := uint32(len(header))
+	var body []byte
+	for i, blob := range blobs {
+		binary.BigEndian.PutUint32(header[12+8*i:], slots[i])
+		binary.BigEndian.PutUint32(header[16+8*i:], offset)
+		offset += uint32(len(blob))
+		body = append(body, blob...)
+	}
+	out := append(header, body...)
+	binary.BigEndian.PutUint32(out[4:], uint32(len(out)))
+	return out
+}
+
+// buildMachO wraps a signature in a minimal 64-bit arm64 executable with a single LC_CODE_SIGNATURE.
+func buildMachO(signature []byte) []byte {
+	const dataOff = 48
+	buf := make([]byte, dataOff)
+	le := binary.LittleEndian
+	le.PutUint32(buf[0:], 0xfeedfacf) // MH_MAGIC_64
+	le.PutUint32(buf[4:], 0x0100000c) // CPU_TYPE_ARM64
+	le.PutUint32(buf[12:], 2)         // MH_EXECUTE
+	le.PutUint32(buf[16:], 1)         // ncmds
+	le.PutUint32(buf[20:], 16)        // sizeofcmds
+	le.PutUint32(buf[32:], lcCodeSignature)
+	le.PutUint32(buf[36:], 16)
+	le.PutUint32(buf[40:], dataOff)
+	le.PutUint32(buf[44:], uint32(len(signature)))
+	return append(buf, signature...)
+}
+
+// buildFat wraps a single slice in a universal binary header.
+func buildFat(slice []byte) []byte {
+	const sliceOff = 4096
+	buf := make([]byte, sliceOff)
+	be := binary.BigEndian
+	be.PutUint32(buf[0:], 0xcafebabe)
+	be.PutUint32(buf[4:], 1)
+	be.PutUint32(buf[8:], 0x0100000c)
+	be.PutUint32(buf[16:], sliceOff)
+	be.PutUint32(buf[20:], uint32(len(slice)))
+	be.PutUint32(buf[24:], 12)
+	return append(buf, slice...)
+}
+
+func writeFixture(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "binary")
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadFile(t *testing.T) {
+	slice := buildMachO(buildSuperblob("com.vendor.app", "ABCDE12345", testEntitlements))
+	fixtures := map[string][]byte{
+		"thin": slice,
+		"fat":  buildFat(slice),
+	}
+
+	for name, data := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			sig, err := ReadFile(writeFixture(t, data))
+			if err != nil {
+				t.Fatalf("ReadFile failed: %v", err)
+			}
+			if sig.Identifier != "com.vendor.app" {
+				t.Errorf("Identifier = %q", sig.Identifier)
+			}
+			if sig.TeamID != "ABCDE12345" {
+				t.Errorf("TeamID = %q", sig.TeamID)
+			}
+			if want := []string{"ABCDE12345.com.vendor.shared", "group.com.vendor.app"}; !reflect.DeepEqual(sig.AppGroups, want) {
+				t.Errorf("AppGroups = %v, want %v", sig.AppGroups, want)
+			}
+			if want := []string{"ABCDE12345.com.vendor.app"}; !reflect.DeepEqual(sig.KeychainGroups, want) {
+				t.Errorf("KeychainGroups = %v, want %v", sig.KeychainGroups, want)
+			}
+			if sig.Entitlements["com.apple.security.app-sandbox"] != true {
+				t.Errorf("Entitlements = %v", sig.Entitlements)
+			}
+		})
+	}
+}
+
+func TestReadFileAdHoc(t *testing.T) {
+	sig, err := ReadFile(writeFixture(t, buildMachO(buildSuperblob("a.out", "", ""))))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if sig.Identifier != "a.out" || sig.TeamID != "" || sig.AppGroups != nil {
+		t.Errorf("Unexpected ad-hoc signature %+v", sig)
+	}
+}
+
+func TestReadFileUnsigned(t *testing.T) {
+	data := buildMachO(nil)
+	binary.LittleEndian.PutUint32(data[16:], 0) // drop the load command
+	binary.LittleEndian.PutUint32(data[20:], 0)
+	if _, err := ReadFile(writeFixture(t, data)); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("ReadFile error = %v, want ErrUnsigned", err)
+	}
+
+	if _, err := ReadFile(writeFixture(t, []byte("#!/bin/sh\n"))); err == nil {
+		t.Error("Expected error for non Mach-O file")
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	valid := buildSuperblob("com.vendor.app", "ABCDE12345", testEntitlements)
+	inputs := map[string][]byte{
+		"empty":     nil,
+		"magic":     bytes.Repeat([]byte{0xff}, 32),
+		"truncated": valid[:40],
+	}
+	for name, input := range inputs {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%s) succeeded, want error", name)
+		}
+	}
+}
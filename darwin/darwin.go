@@ -9,9 +9,10 @@ package darwin
 #cgo LDFLAGS: -framework Foundation
 #include <stdlib.h>
 #include <stdbool.h>
+#include <string.h>
 #include "darwin.mm"
 
-extern bool MoveToTrash(const char *path);
+extern char *MoveToTrash(const char *path);
 extern long long GetFileAllocatedSize(const char *path);
 */
 import "C"
@@ -19,11 +20,19 @@ import "unsafe"
 
 // MoveFileToTrash attempts to use CGo and unsafe to move a file to trash
 // by interfacing with native NSFileManager API
-func MoveFileToTrash(path string) bool {
+//
+// Returns where the file ended up in the Trash
+func MoveFileToTrash(path string) (string, bool) {
 	cPath := C.CString(path)
 	defer C.free(unsafe.Pointer(cPath))
 
-	return bool(C.MoveToTrash(cPath))
+	cTrashed := C.MoveToTrash(cPath)
+	if cTrashed == nil {
+		return "", false
+	}
+	defer C.free(unsafe.Pointer(cTrashed))
+
+	return C.GoString(cTrashed), true
 }
 
 // GetAllocatedFileSize returns the actual disk usage of the file in bytes
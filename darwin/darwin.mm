@@ -3,23 +3,28 @@
 #import <Foundation/Foundation.h>
 
 // Checks if a file exists and if true trashes the file
-bool MoveToTrash(const char *path) {
+//
+// Returns the path the file ended up at in the Trash, which the caller must
+// free, or NULL if the file could not be trashed
+char *MoveToTrash(const char *path) {
     @autoreleasepool {
         NSString *filePath = [NSString stringWithUTF8String:path];
         NSURL *fileURL = [NSURL fileURLWithPath:filePath];
+        NSURL *resultingURL = nil;
         NSError *error = nil;
 
         BOOL success = [[NSFileManager defaultManager]
                         trashItemAtURL:fileURL
-                        resultingItemURL:nil
+                        resultingItemURL:&resultingURL
                         error:&error];
 
         if (!success) {
             const char *errorMsg = [[error localizedDescription] UTF8String];
             fprintf(stderr, "[rmapp] Failed to move %s to Trash\n", path);
+            return NULL;
         }
 
-        return success;
+        return strdup(resultingURL != nil ? [[resultingURL path] UTF8String] : "");
     }
 }
 
@@ -8,24 +8,60 @@ import (
 	"os/exec"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/alewtschuk/pfmt"
-	"github.com/alewtschuk/rmapp/darwin"
 	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/launchd"
 	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/process"
+	"github.com/alewtschuk/rmapp/prompt"
+	"github.com/alewtschuk/rmapp/trash"
 )
 
+// Launchd runs the launchctl commands unloading jobs before removal. Replaced in tests
+var Launchd launchd.Runner = launchd.Launchctl{}
+
+// Processes is where running app processes are looked up. Replaced in tests
+var Processes process.Source = process.Default()
+
+// Trash is the trash backend used unless --trash picks another. Replaced in tests
+var Trash trash.Trash = trash.Default()
+
+// QuitTimeout is how long running processes get to quit before they are killed
+var QuitTimeout = 10 * time.Second
+
+// ErrAborted is returned when the user aborts the removal
+var ErrAborted = errors.New("removal aborted")
+
+// ErrReadOnlyRoot is returned when removing files of a system rebased with --root
+// without explicitly allowing it
+var ErrReadOnlyRoot = errors.New("removal under --root requires --allow-delete")
+
 // Define the Deleter and its fields
 type Deleter struct {
-	matches []string
-	opts    options.Options
+	matches  []*finder.Match
+	opts     options.Options
+	app      string // app recorded in the run's manifest
+	bundleID string // bundle ID recorded in the run's manifest
+	run      string // ID of the recorded run, "" until something was trashed
+	results  []Result
+	onResult func(Result)
 }
 
 // Creates and returns the Deleter
-func NewDeleter(matches []string, opts options.Options) Deleter {
+func NewDeleter(matches []*finder.Match, opts options.Options) Deleter {
+	return NewAppDeleter("", "", matches, opts)
+}
+
+// Creates a Deleter recording the app the matches belong to,
+// so the run can be restored by app name or bundle ID
+func NewAppDeleter(app, bundleID string, matches []*finder.Match, opts options.Options) Deleter {
 	return Deleter{
-		matches: matches,
-		opts:    opts,
+		matches:  matches,
+		opts:     opts,
+		app:      app,
+		bundleID: bundleID,
 	}
 }
 
@@ -33,6 +69,11 @@ func NewDeleter(matches []string, opts options.Options) Deleter {
 //
 // Creates go routine for each individual match.
 func (d *Deleter) Delete() error {
+	if d.opts.Root != "" && !d.opts.AllowDelete {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: "+ErrReadOnlyRoot.Error(), 9))
+		d.recordRest(d.matches, OutcomeSkipped, ErrReadOnlyRoot)
+		return ErrReadOnlyRoot
+	}
 	wg := sync.WaitGroup{}
 
 	var totalSize int64
@@ -46,96 +87,307 @@ func (d *Deleter) Delete() error {
 		isSudo = true
 	}
 
+	all := d.matches
+	d.matches = confirmLowConfidence(d.matches)
+	if len(d.matches) < len(all) {
+		d.recordRest(all, OutcomeSkipped, nil) // low confidence matches the user left alone
+	}
+
+	// Processes and launchd jobs of the running system never run from files under --root
+	if d.opts.Root == "" {
+		running, proceed := confirmQuit(d.matches, d.opts)
+		if !proceed {
+			fmt.Fprintln(prompt.Output, "[rmapp] Removal aborted. Nothing was removed.")
+			d.recordRest(all, OutcomeSkipped, ErrAborted)
+			return ErrAborted
+		}
+		unloadJobs(d.matches)
+		quitProcesses(running)
+	}
+
 	for _, match := range d.matches {
-		size := finder.GetDiskSize(match)
-		totalSize += size
+		totalSize += match.Size(false)
 	}
 
-	log.Println("\n")
+	log.Print("\n\n")
 
 	switch d.opts.Mode {
 	case false: // default trashing behavior
-		var privilegedTrashPaths []string
+		bin, err := d.trash()
+		if err != nil {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: "+err.Error(), 9))
+			d.recordRest(all, OutcomeSkipped, err)
+			return err
+		}
+		// Only the macOS Trash can be escalated to through Finder
+		escalate := bin.Name() == trash.NATIVE
+
+		var privileged []*finder.Match
+		var failed []error
 		mu := sync.Mutex{}
+		manifest := NewManifest(d.app, d.bundleID, bin.Name())
 
 		for _, match := range d.matches {
 			wg.Add(1)
-			go func(path string) {
+			go func(match *finder.Match) {
 				defer wg.Done()
+				path := match.Path
 				if err := exists(path); err != nil {
+					mu.Lock()
+					d.record(match, OutcomeSkipped, err)
+					mu.Unlock()
 					return
 				}
 
-				if isSudo {
+				if isSudo && escalate {
 					// If running with sudo, all trash operations are likely privileged
 					mu.Lock()
-					privilegedTrashPaths = append(privilegedTrashPaths, path)
+					privileged = append(privileged, match)
 					mu.Unlock()
 					return
 				}
 
 				// Try standard, non-privileged trash first
-				if success := darwin.MoveFileToTrash(path); success {
+				trashed, err := bin.MoveToTrash(path)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
 					log.Printf("Successfully moved %s to Trash 🗑️\n", pfmt.ApplyColor(path, 3))
-				} else {
+					manifest.Add(path, trashed)
+					d.recordTrashed(match, trashed, OutcomeTrashed, nil)
+				case escalate:
 					// Assume elevated permissions if fails
-					mu.Lock()
-					privilegedTrashPaths = append(privilegedTrashPaths, path)
-					mu.Unlock()
+					privileged = append(privileged, match)
+				default:
+					fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: "+path+" could not be moved to the Trash: "+err.Error(), 9))
+					failed = append(failed, err)
+					d.record(match, OutcomeFailed, err)
 				}
 			}(match)
 		}
 		wg.Wait()
 
-		if len(privilegedTrashPaths) > 0 {
-			if err := RunPrivilegedTrash(privilegedTrashPaths, d.opts.Verbosity, sudoUser); err != nil {
-				// The error is already logged in the function, just exit.
-				return err
+		var trashErr error
+		if len(privileged) > 0 {
+			trashed, err := RunPrivilegedTrash(finder.Paths(privileged), d.opts.Verbosity, sudoUser)
+			for i, path := range trashed {
+				manifest.Add(privileged[i].Path, path)
+				d.recordTrashed(privileged[i], path, OutcomeTrashed, nil)
+			}
+			trashErr = err // the error is already logged in the function
+			if err != nil {
+				d.recordRest(privileged, OutcomeFailed, err)
+			} else {
+				if len(trashed) < len(privileged) {
+					fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] WARN: Could not locate the escalated files in the Trash. They cannot be restored with 'rmapp restore'", 3))
+				}
+				d.recordRest(privileged, OutcomeTrashed, nil)
 			}
 		}
 
+		d.run = recordRun(manifest)
+		if trashErr != nil {
+			return trashErr
+		}
+		if len(failed) > 0 {
+			return errors.Join(failed...)
+		}
+
 	case true: // -f or --force full removal enabled
-		var protectedPaths []string
-		mu := sync.Mutex{}
+		if err := d.forceDelete(); err != nil {
+			return err
+		}
+	}
 
-		for _, match := range d.matches {
-			wg.Add(1)
-			go func(path string) {
-				defer wg.Done()
+	fmt.Fprintf(prompt.Output, "Total: %s has been freed\n\n", finder.FormatSize(totalSize))
 
-				if err := exists(path); err != nil {
-					return
-				}
+	return nil
+}
 
-				if rmErr := os.RemoveAll(path); rmErr != nil {
-					if errors.Is(rmErr, os.ErrPermission) {
-						mu.Lock()
-						protectedPaths = append(protectedPaths, path)
-						mu.Unlock()
-						return
-					}
-					fmt.Println(pfmt.ApplyColor("[rmapp] ERROR: "+path+" could not be deleted", 9))
-				}
+// Returns the trash backend picked with --trash or the default one
+func (d *Deleter) trash() (trash.Trash, error) {
+	if d.opts.Trash == "" {
+		return Trash, nil
+	}
+	return trash.ByName(d.opts.Trash)
+}
 
-				log.Printf("Successfully deleted %s 💥\n", pfmt.ApplyColor(path, 3))
+// Saves the run's manifest and tells the user how to undo it.
+//
+// Returns the ID of the run or "" if nothing was recorded
+func recordRun(manifest *Manifest) string {
+	if len(manifest.Items) == 0 {
+		return ""
+	}
+	if err := manifest.Save(); err != nil {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] WARN: could not record run for restore: "+err.Error(), 3))
+		return ""
+	}
+	fmt.Fprintf(prompt.Output, "[rmapp] Undo with: rmapp restore %s\n", pfmt.ApplyColor(manifest.Run, 2))
+	return manifest.Run
+}
 
-			}(match)
-		}
-		wg.Wait() // block till all routines have returned
+// Removes the matches as a whole by staging them all before purging.
+//
+// If any match cannot be staged every staged match is restored. Permission
+// protected matches cannot be staged, so they are only deleted with
+// elevated permissions once the staged matches are purged
+func (d *Deleter) forceDelete() error {
+	tx, err := NewTransaction()
+	if err != nil {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: could not start removal journal: "+err.Error(), 9))
+		d.recordRest(d.matches, OutcomeSkipped, err)
+		return err
+	}
 
-		if len(protectedPaths) > 0 {
-			if err := RunPrivilegedDelete(protectedPaths, d.opts.Verbosity); err != nil {
-				return err
+	var protected []*finder.Match
+	var failed error
+	for _, match := range d.matches {
+		if err := exists(match.Path); err != nil {
+			d.record(match, OutcomeSkipped, err)
+			continue
+		}
+		if err := tx.Stage(match.Path); err != nil {
+			if errors.Is(err, os.ErrPermission) {
+				protected = append(protected, match)
+				continue
 			}
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: "+match.Path+" could not be deleted: "+err.Error(), 9))
+			d.record(match, OutcomeFailed, err)
+			failed = err
+			break
+		}
+	}
+
+	if failed != nil {
+		if err := tx.Rollback(); err != nil {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: rollback incomplete: "+err.Error(), 9))
+			d.recordRest(d.matches, OutcomeRolledBack, err)
+			return failed
 		}
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Removal rolled back. Staged files were restored.", 3))
+		d.recordRest(d.matches, OutcomeRolledBack, nil)
+		return failed
 	}
 
-	fmt.Printf("Total: %s has been freed\n\n", finder.FormatSize(totalSize))
+	if err := tx.Commit(func(paths []string) error {
+		return RunPrivilegedDelete(paths, d.opts.Verbosity)
+	}); err != nil {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: staged files could not be purged, retrying on next run: "+err.Error(), 9))
+		d.recordRest(d.matches, OutcomeFailed, err)
+		return err
+	}
+	for _, move := range tx.Moves() {
+		log.Printf("Successfully deleted %s 💥\n", pfmt.ApplyColor(move.Original, 3))
+	}
 
+	if len(protected) > 0 {
+		if err := RunPrivilegedDelete(finder.Paths(protected), d.opts.Verbosity); err != nil {
+			d.recordRest(protected, OutcomeFailed, err)
+			d.recordRest(d.matches, OutcomeDeleted, nil)
+			return err
+		}
+	}
+	d.recordRest(d.matches, OutcomeDeleted, nil)
 	return nil
 }
 
-// Checks if file/directory exists
+// Finds processes running from the matches and asks whether to quit them
+// or abort, skipping the question with --kill.
+//
+// Returns the processes to quit and false if the removal was aborted
+func confirmQuit(matches []*finder.Match, opts options.Options) ([]process.Process, bool) {
+	running, err := process.Running(Processes, finder.Paths(matches))
+	if err != nil {
+		log.Printf("Could not list running processes: %v", err)
+		return nil, true
+	}
+	if len(running) == 0 {
+		return nil, true
+	}
+
+	fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] The following processes are running from files about to be removed:", 3))
+	for _, proc := range running {
+		fmt.Fprintf(prompt.Output, "  • %s (pid %d) %s\n", pfmt.ApplyColor(proc.Name(), 2), proc.PID, pfmt.ApplyColor(proc.Executable, 3))
+	}
+	if opts.Kill {
+		return running, true
+	}
+
+	idx, ok := prompt.Choose("Quit them before removal?", []string{
+		fmt.Sprintf("Quit, force quitting after %s", QuitTimeout),
+		"Abort removal",
+	})
+	return running, ok && idx == 0
+}
+
+// Quits the processes, force quitting the ones that do not exit in time
+func quitProcesses(running []process.Process) {
+	if len(running) == 0 {
+		return
+	}
+	remaining := process.Quit(Processes, running, QuitTimeout)
+	for _, proc := range remaining {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor(fmt.Sprintf("[rmapp] WARN: %s (pid %d) is still running", proc.Name(), proc.PID), 3))
+	}
+	if len(remaining) == 0 {
+		log.Printf("Quit %d running processes\n", len(running))
+	}
+}
+
+// Unloads the launchd jobs among the matches so they are not
+// running or relaunched while their files are removed
+func unloadJobs(matches []*finder.Match) {
+	for _, match := range matches {
+		if !launchd.IsJobPath(match.Path) {
+			continue
+		}
+		job, err := launchd.ReadJob(match.Path)
+		if err != nil {
+			continue
+		}
+		if err := launchd.Bootout(Launchd, job); err != nil {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] WARN: could not unload launchd job "+job.Label+": "+err.Error(), 3))
+			continue
+		}
+		log.Printf("Unloaded launchd job %s\n", pfmt.ApplyColor(job.Domain(), 3))
+	}
+}
+
+// Asks before removing matches with low confidence scores.
+//
+// Returns the matches to go ahead with, dropping the low
+// confidence ones unless the user explicitly confirms
+func confirmLowConfidence(matches []*finder.Match) []*finder.Match {
+	var confident, low []*finder.Match
+	for _, match := range matches {
+		if match.Confidence < finder.LOW_CONFIDENCE {
+			low = append(low, match)
+		} else {
+			confident = append(confident, match)
+		}
+	}
+	if len(low) == 0 {
+		return matches
+	}
+
+	fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] The following matches have low confidence and may belong to another app:", 3))
+	for _, match := range low {
+		fmt.Fprintf(prompt.Output, "  • %s %s (%s)\n", finder.FormatConfidence(match.Confidence), pfmt.ApplyColor(match.Path, 3), match.Rule)
+	}
+
+	if prompt.Confirm("Remove these too?") {
+		return matches
+	}
+	fmt.Fprintln(prompt.Output, "[rmapp] Skipping low confidence matches")
+	return confident
+}
+
+// Checks if file/directory exists.
+//
+// Always asks the filesystem directly, never the scan index,
+// as this is the last check before a path is removed
 func exists(match string) error {
 	_, err := os.Stat(match) // explicitly used for error only
 	if err == nil {
@@ -143,21 +395,23 @@ func exists(match string) error {
 	}
 
 	if os.IsNotExist(err) {
-		fmt.Printf("File %s does not exist. Skipping...\n", pfmt.ApplyColor(match, 3))
+		fmt.Fprintf(prompt.Output, "File %s does not exist. Skipping...\n", pfmt.ApplyColor(match, 3))
 		return err
 	} else {
-		fmt.Println("[rmapp] Error:", err)
+		fmt.Fprintln(prompt.Output, "[rmapp] Error:", err)
 		return err
 	}
 }
 
 // RunPrivilegedTrash moves a list of files/directories to the Trash using AppleScript with elevated privileges.
-func RunPrivilegedTrash(paths []string, verbose bool, sudoUser string) error {
+//
+// Returns where each path ended up in the Trash, in the order of paths
+func RunPrivilegedTrash(paths []string, verbose bool, sudoUser string) ([]string, error) {
 	if len(paths) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	fmt.Println(pfmt.ApplyColor("WARN: Some files require elevated permissions to be moved to the Trash. Escalating with osascript…", 3))
+	fmt.Fprintln(prompt.Output, pfmt.ApplyColor("WARN: Some files require elevated permissions to be moved to the Trash. Escalating with osascript…", 3))
 
 	var posixFiles []string
 	for _, path := range paths {
@@ -165,9 +419,16 @@ func RunPrivilegedTrash(paths []string, verbose bool, sudoUser string) error {
 	}
 	appleScriptList := fmt.Sprintf("{%s}", strings.Join(posixFiles, ", "))
 
-	// This script tells Finder to move the list of files to the trash
-	// It will prompt for a password if necessary
-	appleScript := fmt.Sprintf(`tell application "Finder" to delete %s`, appleScriptList)
+	// This script tells Finder to move the list of files to the trash one by one,
+	// printing where each ended up. It will prompt for a password if necessary
+	appleScript := fmt.Sprintf(`set trashed to {}
+tell application "Finder"
+	repeat with f in %s
+		set end of trashed to POSIX path of ((delete (contents of f)) as alias)
+	end repeat
+end tell
+set AppleScript's text item delimiters to linefeed
+return trashed as text`, appleScriptList)
 
 	var cmd *exec.Cmd
 	if sudoUser != "" {
@@ -179,9 +440,10 @@ func RunPrivilegedTrash(paths []string, verbose bool, sudoUser string) error {
 		cmd = exec.Command("osascript", "-e", appleScript)
 	}
 
-	if err := cmd.Run(); err != nil {
-		fmt.Println(pfmt.ApplyColor("[rmapp] ERROR: privileged trash failed. Some files may not have been moved.", 9))
-		return err
+	out, err := cmd.Output()
+	if err != nil {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: privileged trash failed. Some files may not have been moved.", 9))
+		return nil, err
 	}
 
 	if verbose {
@@ -189,7 +451,15 @@ func RunPrivilegedTrash(paths []string, verbose bool, sudoUser string) error {
 			log.Printf("Successfully moved %s to Trash 🗑️\n", pfmt.ApplyColor(path, 3))
 		}
 	}
-	return nil
+
+	trashed := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(trashed) != len(paths) {
+		return nil, nil // locations could not be attributed, the files are trashed but not restorable
+	}
+	for i := range trashed {
+		trashed[i] = strings.TrimSuffix(trashed[i], "/") // folders are printed with a trailing slash
+	}
+	return trashed, nil
 }
 
 // RunPrivilegedDelete deletes a list of files/directories using AppleScript with elevated privileges.
@@ -199,7 +469,7 @@ func RunPrivilegedDelete(paths []string, verbose bool) error {
 		return nil
 	}
 
-	fmt.Println(pfmt.ApplyColor("WARN: Some files are permission protected. Escalating with osascript…", 3))
+	fmt.Fprintln(prompt.Output, pfmt.ApplyColor("WARN: Some files are permission protected. Escalating with osascript…", 3))
 
 	var quoted []string
 	for _, path := range paths {
@@ -211,13 +481,13 @@ func RunPrivilegedDelete(paths []string, verbose bool) error {
 		fmt.Sprintf(`do shell script "rm -rf %s" with administrator privileges`, joined))
 
 	if err := cmd.Run(); err != nil {
-		fmt.Println(pfmt.ApplyColor("[rmapp] ERROR: privileged delete failed", 9))
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: privileged delete failed", 9))
 		return err
 	}
 
 	if verbose {
 		for _, path := range paths {
-			fmt.Printf("Successfully deleted %s 💥\n", pfmt.ApplyColor(path, 3))
+			fmt.Fprintf(prompt.Output, "Successfully deleted %s 💥\n", pfmt.ApplyColor(path, 3))
 		}
 	}
 
@@ -0,0 +1,187 @@
+package deleter
+
+/*
+Manifest.go holds the per-run record of everything rmapp moved to the Trash
+so a removal can be undone with rmapp restore
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alewtschuk/rmapp/trash"
+)
+
+const RUNS_DIR string = "runs" // name of the manifest directory in the config directory
+
+// ManifestDir is where the manifests of trashing runs are kept,
+// defaulting to the rmapp config directory. Replaced in tests
+var ManifestDir string
+
+var (
+	// ErrConflict is returned when something new occupies the original path of a trashed item
+	ErrConflict = trash.ErrOccupied
+	// ErrMissing is returned when a trashed item is no longer in the Trash
+	ErrMissing = trash.ErrNotInTrash
+	// ErrNoRun is returned when no recorded run matches the restore query
+	ErrNoRun = errors.New("no matching run")
+)
+
+// Manifest records where every item of a trashing run ended up
+type Manifest struct {
+	Run      string        `json:"run"`
+	App      string        `json:"app,omitempty"`
+	BundleID string        `json:"bundle_id,omitempty"`
+	Backend  string        `json:"backend,omitempty"` // trash backend the items were moved to
+	Created  time.Time     `json:"created"`
+	Items    []TrashedItem `json:"items"`
+	path     string
+}
+
+// TrashedItem is a single file or folder moved to the Trash
+type TrashedItem struct {
+	Original string `json:"original"`
+	Trashed  string `json:"trashed"`
+}
+
+// RestoreResult is the outcome of restoring a single item
+type RestoreResult struct {
+	Item TrashedItem
+	Err  error
+}
+
+// Creates an empty manifest for a new run trashing into the backend
+func NewManifest(app, bundleID, backend string) *Manifest {
+	return &Manifest{Run: newRunID(), App: app, BundleID: bundleID, Backend: backend, Created: time.Now()}
+}
+
+// Records an item moved to the Trash
+func (m *Manifest) Add(original, trashed string) {
+	m.Items = append(m.Items, TrashedItem{Original: original, Trashed: trashed})
+}
+
+// Writes the manifest to the manifest directory, removing it once no items are left
+func (m *Manifest) Save() error {
+	if m.path == "" {
+		dir, err := manifestDir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		m.path = filepath.Join(dir, m.Run+".json")
+	}
+	if len(m.Items) == 0 {
+		if err := os.Remove(m.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// Moves every item of the run back to its original location out of bin.
+//
+// Items whose original location has been reoccupied are left in the
+// Trash and reported with ErrConflict. Restored and missing items are
+// dropped from the manifest so the conflicts can be retried later
+func (m *Manifest) Restore(bin trash.Trash) []RestoreResult {
+	var results []RestoreResult
+	var remaining []TrashedItem
+
+	for _, item := range m.Items {
+		err := ErrMissing
+		if item.Trashed != "" {
+			err = bin.Restore(item.Trashed, item.Original)
+		}
+		results = append(results, RestoreResult{Item: item, Err: err})
+		if err != nil && !errors.Is(err, ErrMissing) {
+			remaining = append(remaining, item)
+		}
+	}
+
+	m.Items = remaining
+	return results
+}
+
+// Returns every recorded run, newest first
+func Manifests() ([]Manifest, error) {
+	dir, err := manifestDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []Manifest
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			continue
+		}
+		manifest.path = path
+		manifests = append(manifests, manifest)
+	}
+
+	slices.SortFunc(manifests, func(a, b Manifest) int {
+		return b.Created.Compare(a.Created)
+	})
+	return manifests, nil
+}
+
+// Finds the newest run matching the query.
+//
+// The query may be a run ID, an app name with or without .app or a
+// bundle ID. An empty query matches the newest run
+func FindManifest(query string) (Manifest, error) {
+	manifests, err := Manifests()
+	if err != nil {
+		return Manifest{}, err
+	}
+	name := strings.TrimSuffix(query, ".app")
+	for _, manifest := range manifests {
+		if query == "" || manifest.Run == query ||
+			strings.EqualFold(strings.TrimSuffix(manifest.App, ".app"), name) ||
+			strings.EqualFold(manifest.BundleID, query) {
+			return manifest, nil
+		}
+	}
+	if query == "" {
+		return Manifest{}, ErrNoRun
+	}
+	return Manifest{}, fmt.Errorf("%w for %q", ErrNoRun, query)
+}
+
+// Returns the directory holding the manifests
+func manifestDir() (string, error) {
+	if ManifestDir != "" {
+		return ManifestDir, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "rmapp", RUNS_DIR), nil
+}
@@ -0,0 +1,75 @@
+package deleter
+
+/*
+Result.go holds the outcome of removing every single match, so a run can
+be reported item by item instead of only as a freed total
+*/
+
+import "github.com/alewtschuk/rmapp/finder"
+
+// Outcomes of removing a single match
+const (
+	OutcomeTrashed    string = "trashed"     // moved to the Trash
+	OutcomeDeleted    string = "deleted"     // removed for good
+	OutcomeSkipped    string = "skipped"     // left in place, as it was gone already or not confirmed
+	OutcomeFailed     string = "failed"      // could not be removed
+	OutcomeRolledBack string = "rolled back" // left in place as another match could not be removed
+)
+
+// Result is the outcome of removing a single match
+type Result struct {
+	Match   *finder.Match
+	Outcome string
+	Trashed string // where the match ended up in the Trash, "" unless trashed
+	Err     error
+}
+
+// Freed returns the bytes the removal freed
+func (r Result) Freed() int64 {
+	if r.Outcome != OutcomeTrashed && r.Outcome != OutcomeDeleted {
+		return 0
+	}
+	return r.Match.Size(false)
+}
+
+// Results returns the outcome of every match handled by Delete
+func (d *Deleter) Results() []Result {
+	return d.results
+}
+
+// Run returns the ID the run was recorded under for rmapp restore, "" if nothing was trashed
+func (d *Deleter) Run() string {
+	return d.run
+}
+
+// OnResult calls fn with every outcome as soon as it is known, used to stream results
+func (d *Deleter) OnResult(fn func(Result)) {
+	d.onResult = fn
+}
+
+// Records the outcome of a match. Callers removing matches concurrently hold their lock
+func (d *Deleter) record(match *finder.Match, outcome string, err error) {
+	d.recordTrashed(match, "", outcome, err)
+}
+
+// Records the outcome of a match along with where it ended up in the Trash
+func (d *Deleter) recordTrashed(match *finder.Match, trashed, outcome string, err error) {
+	result := Result{Match: match, Outcome: outcome, Trashed: trashed, Err: err}
+	d.results = append(d.results, result)
+	if d.onResult != nil {
+		d.onResult(result)
+	}
+}
+
+// Records the outcome for every match that has none yet
+func (d *Deleter) recordRest(matches []*finder.Match, outcome string, err error) {
+	done := map[*finder.Match]bool{}
+	for _, result := range d.results {
+		done[result.Match] = true
+	}
+	for _, match := range matches {
+		if !done[match] {
+			d.record(match, outcome, err)
+		}
+	}
+}
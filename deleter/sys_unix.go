@@ -0,0 +1,29 @@
+//go:build unix
+
+package deleter
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ProcessStart returns when the process with the pid started, "" if unknown.
+// Replaced in tests
+var ProcessStart = psStart
+
+// Checks if another process with the pid is still running
+func isRunning(pid int) bool {
+	return pid > 0 && pid != os.Getpid() && syscall.Kill(pid, 0) == nil
+}
+
+// Reads the start time of the process from ps
+func psStart(pid int) string {
+	out, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
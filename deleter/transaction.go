@@ -0,0 +1,339 @@
+package deleter
+
+/*
+Transaction.go holds the two phase removal used by --force. Every target is
+first renamed into a staging directory on its own volume while a journal
+records each move. Only once everything is staged is the staging area purged,
+otherwise the moves are undone. A journal left behind by an interrupted run
+is rolled back or finished on the next start
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/prompt"
+	"github.com/alewtschuk/rmapp/trash"
+)
+
+const (
+	STAGING_DIR string = ".rmapp-staging" // staging directory created at the root of each volume
+	JOURNAL_DIR string = "journal"        // name of the journal directory in the cache directory
+)
+
+// States of a journaled removal
+const (
+	StateStaging    = "staging"    // targets are being staged, rolled back on recovery
+	StateCommitting = "committing" // every target is staged, purged on recovery
+)
+
+// JournalDir is where the journals of removals in progress are kept,
+// defaulting to the rmapp cache directory. Replaced in tests
+var JournalDir string
+
+// Journal records the moves of a removal so an interrupted run can be undone or finished
+type Journal struct {
+	Run     string   `json:"run"`
+	PID     int      `json:"pid"`               // process running the removal
+	Started string   `json:"started,omitempty"` // when that process started, telling it apart from a later one reusing the PID
+	State   string   `json:"state"`
+	Staging []string `json:"staging"` // staging directories created by the run
+	Moves   []Move   `json:"moves"`
+	path    string
+}
+
+// Move is a single target renamed into staging
+type Move struct {
+	Original string `json:"original"`
+	Staged   string `json:"staged"`
+}
+
+// Transaction stages targets for removal and purges or restores them as a whole
+type Transaction struct {
+	journal Journal
+	staging map[string]string // staging directory per volume root or parent folder
+}
+
+// Starts a transaction and writes its empty journal
+func NewTransaction() (*Transaction, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	run := newRunID()
+	tx := &Transaction{
+		journal: Journal{Run: run, PID: os.Getpid(), Started: ProcessStart(os.Getpid()), State: StateStaging, path: filepath.Join(dir, run+".json")},
+		staging: map[string]string{},
+	}
+	return tx, tx.journal.save()
+}
+
+// Returns the moves staged so far
+func (tx *Transaction) Moves() []Move {
+	return tx.journal.Moves
+}
+
+// Renames the path into staging on the same volume.
+//
+// The move is journaled before the rename so a crash in between is
+// still recoverable. Falls back to staging next to the path when the
+// volume root is not writable or is a different filesystem
+func (tx *Transaction) Stage(path string) error {
+	dir, err := tx.stagingFor(path, false)
+	if err != nil {
+		return err
+	}
+	err = tx.move(path, dir)
+	if errors.Is(err, syscall.EXDEV) {
+		if dir, err = tx.stagingFor(path, true); err == nil {
+			err = tx.move(path, dir)
+		}
+	}
+	return err
+}
+
+// Journals and performs a single rename, forgetting it again if it failed
+func (tx *Transaction) move(path, dir string) error {
+	staged := filepath.Join(dir, fmt.Sprintf("%d-%s", len(tx.journal.Moves), filepath.Base(path)))
+	tx.journal.Moves = append(tx.journal.Moves, Move{Original: path, Staged: staged})
+	if err := tx.journal.save(); err != nil {
+		tx.journal.Moves = tx.journal.Moves[:len(tx.journal.Moves)-1]
+		return err
+	}
+
+	if err := os.Rename(path, staged); err != nil {
+		tx.journal.Moves = tx.journal.Moves[:len(tx.journal.Moves)-1]
+		tx.journal.save()
+		return err
+	}
+	return nil
+}
+
+// Returns the staging directory for path, creating it on first use.
+//
+// Prefers a single directory at the root of the path's volume and uses
+// one next to the path when sibling is set or the root is not writable
+func (tx *Transaction) stagingFor(path string, sibling bool) (string, error) {
+	if !sibling {
+		if root := trash.VolumeRoot(path); root != "" {
+			if dir, ok := tx.staging[root]; ok {
+				return dir, nil
+			}
+			dir := filepath.Join(root, STAGING_DIR, tx.journal.Run)
+			if err := tx.createStaging(dir); err == nil {
+				tx.staging[root] = dir
+				return dir, nil
+			}
+		}
+	}
+
+	parent := filepath.Dir(path)
+	if dir, ok := tx.staging[parent]; ok {
+		return dir, nil
+	}
+	dir := filepath.Join(parent, STAGING_DIR+"-"+tx.journal.Run)
+	if err := tx.createStaging(dir); err != nil {
+		return "", err
+	}
+	tx.staging[parent] = dir
+	return dir, nil
+}
+
+// Creates a staging directory and journals it
+func (tx *Transaction) createStaging(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tx.journal.Staging = append(tx.journal.Staging, dir)
+	return tx.journal.save()
+}
+
+// Moves every staged target back to where it came from.
+//
+// Targets are restored in reverse order and never over
+// something that has since been created at the original path
+func (tx *Transaction) Rollback() error {
+	return tx.journal.rollback()
+}
+
+// Purges the staging directories, handing the ones that need elevated
+// permissions to privileged when it is set.
+//
+// The journal is only removed once everything is gone, so a failed
+// purge is retried on the next start
+func (tx *Transaction) Commit(privileged func([]string) error) error {
+	tx.journal.State = StateCommitting
+	if err := tx.journal.save(); err != nil {
+		return err
+	}
+	return tx.journal.purge(privileged)
+}
+
+// Restores the moves of the journal and removes it if all were restored
+func (j *Journal) rollback() error {
+	var errs []error
+	for i := len(j.Moves) - 1; i >= 0; i-- {
+		move := j.Moves[i]
+		if _, err := os.Lstat(move.Staged); os.IsNotExist(err) {
+			continue // crashed before the rename happened
+		}
+		if _, err := os.Lstat(move.Original); err == nil {
+			errs = append(errs, fmt.Errorf("%s already exists, staged copy kept at %s", move.Original, move.Staged))
+			continue
+		}
+		if err := os.Rename(move.Staged, move.Original); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Restored %s\n", pfmt.ApplyColor(move.Original, 3))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	j.removeStaging()
+	return os.Remove(j.path)
+}
+
+// Removes the staging directories and the journal
+func (j *Journal) purge(privileged func([]string) error) error {
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+	var protected []string
+	var errs []error
+
+	for _, dir := range j.Staging {
+		wg.Add(1)
+		go func(dir string) {
+			defer wg.Done()
+			if err := os.RemoveAll(dir); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				if errors.Is(err, os.ErrPermission) && privileged != nil {
+					protected = append(protected, dir)
+					return
+				}
+				errs = append(errs, err)
+			}
+		}(dir)
+	}
+	wg.Wait()
+
+	if len(protected) > 0 {
+		if err := privileged(protected); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	j.removeStaging()
+	return os.Remove(j.path)
+}
+
+// Removes the now empty staging directories and their per volume parents
+func (j *Journal) removeStaging() {
+	for _, dir := range j.Staging {
+		os.Remove(dir)
+		if filepath.Base(filepath.Dir(dir)) == STAGING_DIR {
+			os.Remove(filepath.Dir(dir)) // only succeeds once no other run uses it
+		}
+	}
+}
+
+// Writes the journal to a temporary file and renames it into place
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+// Recover finishes or undoes the removals whose journals were left behind
+// by an interrupted run.
+//
+// Runs that were still staging are rolled back, runs that had staged
+// everything are purged. Runs whose process is still alive are left alone,
+// unless the PID now belongs to another process after a reboot or reuse
+func Recover() {
+	dir, err := journalDir()
+	if err != nil {
+		return
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var journal Journal
+		if err := json.Unmarshal(data, &journal); err != nil {
+			log.Printf("Could not read removal journal %s: %v", path, err)
+			continue
+		}
+		journal.path = path
+		if journal.isRunning() {
+			continue // still in progress in another rmapp
+		}
+
+		switch journal.State {
+		case StateCommitting:
+			fmt.Fprintf(prompt.Output, "[rmapp] Finishing interrupted removal %s...\n", pfmt.ApplyColor(journal.Run, 3))
+			err = journal.purge(nil)
+		default:
+			fmt.Fprintf(prompt.Output, "[rmapp] Rolling back interrupted removal %s...\n", pfmt.ApplyColor(journal.Run, 3))
+			err = journal.rollback()
+		}
+		if err != nil {
+			fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] ERROR: could not recover removal "+journal.Run+": "+err.Error(), 9))
+		}
+	}
+}
+
+// Checks if the process that wrote the journal is still running
+func (j *Journal) isRunning() bool {
+	if !isRunning(j.PID) {
+		return false
+	}
+	started := ProcessStart(j.PID)
+	return j.Started == "" || started == "" || started == j.Started
+}
+
+// Returns the directory holding the journals
+func journalDir() (string, error) {
+	if JournalDir != "" {
+		return JournalDir, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "rmapp", JOURNAL_DIR), nil
+}
+
+// Returns a sortable identifier unique to this run
+func newRunID() string {
+	return strings.ReplaceAll(time.Now().Format("20060102-150405.000"), ".", "-") + fmt.Sprintf("-%d", os.Getpid())
+}
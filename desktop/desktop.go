@@ -0,0 +1,166 @@
+// Package desktop reads freedesktop.org desktop entries, the .desktop files
+// Linux desktops list installed applications with.
+package desktop
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	EXTENSION string = ".desktop"      // extension of desktop entry files
+	GROUP     string = "Desktop Entry" // group holding the keys of the entry
+	FLATPAK   string = "X-Flatpak"     // key Flatpak exports carry the app ID in
+	LAUNCHER  string = "flatpak"       // command Flatpak exports are started with
+	MAX_LINE  int    = 64 * 1024       // longest line read from an entry
+)
+
+// ErrNoEntry is returned for files without a [Desktop Entry] group
+var ErrNoEntry = errors.New("no [Desktop Entry] group")
+
+// Entry is the metadata of an installed application read from its desktop entry
+type Entry struct {
+	ID      string // desktop file ID, the file name without .desktop
+	Name    string // Name shown in menus
+	Exec    string // command line the app is started with
+	TryExec string // executable checked for before the entry is shown
+	Flatpak string // X-Flatpak app ID of apps installed with Flatpak
+	Hidden  bool   // entry was deleted by the user, hiding entries of the same ID
+}
+
+// Reports if path names a desktop entry
+func IsEntryPath(path string) bool {
+	return strings.HasSuffix(path, EXTENSION)
+}
+
+// ReadFile reads the desktop entry at path
+func ReadFile(path string) (Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer file.Close()
+
+	entry, err := Parse(file)
+	entry.ID = strings.TrimSuffix(filepath.Base(path), EXTENSION)
+	return entry, err
+}
+
+// Parse reads the keys of the [Desktop Entry] group.
+//
+// Localized keys such as Name[de] and every other group are ignored
+func Parse(r io.Reader) (Entry, error) {
+	var entry Entry
+	found := false
+	group := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, MAX_LINE)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = line[1 : len(line)-1]
+			found = found || group == GROUP
+			continue
+		}
+		if group != GROUP {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Name":
+			entry.Name = value
+		case "Exec":
+			entry.Exec = value
+		case "TryExec":
+			entry.TryExec = value
+		case FLATPAK:
+			entry.Flatpak = value
+		case "Hidden":
+			entry.Hidden = value == "true"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entry, err
+	}
+	if !found {
+		return entry, ErrNoEntry
+	}
+	return entry, nil
+}
+
+// AppID returns the application ID, the Flatpak app ID or else the desktop file ID
+func (e Entry) AppID() string {
+	if e.Flatpak != "" {
+		return e.Flatpak
+	}
+	return e.ID
+}
+
+// Executable returns the file name of the program the entry starts.
+//
+// Leading env assignments are skipped and "" is returned for Flatpak
+// launchers as the app ID already covers their data
+func (e Entry) Executable() string {
+	args := splitExec(e.Exec)
+	if len(args) > 0 && filepath.Base(args[0]) == "env" {
+		args = args[1:]
+		for len(args) > 0 && strings.Contains(args[0], "=") {
+			args = args[1:]
+		}
+	}
+
+	program := e.TryExec
+	if len(args) > 0 {
+		program = args[0]
+	}
+	if program == "" || filepath.Base(program) == LAUNCHER {
+		return ""
+	}
+	return filepath.Base(program)
+}
+
+// Splits an Exec value into its arguments, honouring double quotes
+// and backslash escapes inside them
+func splitExec(exec string) []string {
+	var args []string
+	var current strings.Builder
+	quoted, started := false, false
+
+	for i := 0; i < len(exec); i++ {
+		c := exec[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(exec):
+			i++
+			current.WriteByte(exec[i])
+		case c == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (c == ' ' || c == '\t'):
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteByte(c)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,60 @@
+package desktop
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "org.gemini.Test.desktop")
+	data := `# Written by the installer
+[Desktop Entry]
+Type=Application
+Name=Test App
+Name[de]=Testanwendung
+Exec=env GDK_BACKEND=x11 "/opt/Test App/testapp" --new-window %U
+
+[Desktop Action new-window]
+Name=New Window
+Exec=/opt/other %U
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.ID != "org.gemini.Test" || entry.AppID() != "org.gemini.Test" {
+		t.Errorf("Expected ID org.gemini.Test, got %q and app ID %q", entry.ID, entry.AppID())
+	}
+	if entry.Name != "Test App" || entry.Hidden {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if entry.Executable() != "testapp" {
+		t.Errorf("Expected executable testapp, got %q", entry.Executable())
+	}
+}
+
+func TestFlatpak(t *testing.T) {
+	entry, err := Parse(strings.NewReader(`[Desktop Entry]
+Name=Firefox
+Exec=/usr/bin/flatpak run --branch=stable --command=firefox org.mozilla.firefox @@u %u @@
+X-Flatpak=org.mozilla.firefox
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.AppID() != "org.mozilla.firefox" || entry.Executable() != "" {
+		t.Errorf("Expected the Flatpak app ID and no executable, got %q and %q", entry.AppID(), entry.Executable())
+	}
+
+	if _, err := Parse(strings.NewReader("[Trash Info]\nPath=/tmp/x\n")); !errors.Is(err, ErrNoEntry) {
+		t.Errorf("Expected ErrNoEntry, got %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+package finder
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/alewtschuk/rmapp/index"
+	"github.com/alewtschuk/rmapp/launchd"
+	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/prompt"
+)
+
+// Footprint holds every entry attributed to an app
+type Footprint struct {
+	Target  Target   // app the entries were attributed to
+	Matches []*Match // the app's bundle and associated files
+}
+
+// Returns the size of the app's own bundle and of its associated files
+func (fp Footprint) Sizes(logical bool) (int64, int64) {
+	var bundle, data int64
+	for _, match := range fp.Matches {
+		if match.Kind == KindBundle {
+			bundle += match.Size(logical)
+		} else {
+			data += match.Size(logical)
+		}
+	}
+	return bundle, data
+}
+
+// Returns the combined size of the app's bundle and associated files
+func (fp Footprint) Size(logical bool) int64 {
+	bundle, data := fp.Sizes(logical)
+	return bundle + data
+}
+
+// Match sent from a root's walk along with the index of its target
+type attributed struct {
+	match *Match
+	owner int
+}
+
+// attributor matches entries against the identifiers of many targets at once
+type attributor struct {
+	finder  Finder
+	targets []Target
+	ids     *automaton[byte]   // lowercased bundle IDs, their numeric suffix bases and identifiers
+	names   *automaton[string] // tokenized app names
+	bundles map[string]int     // bundle path to its target
+	byID    map[string]int     // lowercased bundle ID to the first target declaring it
+}
+
+// Attribute walks every search root once and attributes each entry to the
+// target it most likely belongs to, returning a footprint per target in the
+// order of targets.
+//
+// Instead of running the matcher once per target, the names are fed through
+// combined automatons of all targets' identifiers and app names to find the
+// few candidate targets, which are then confirmed with the same rules and
+// confidence scores as FindMatches, including launchd jobs running an app
+// and the files of the packages that installed it. An entry matching several
+// targets goes to the highest confidence match, and a matched directory is
+// attributed as a whole
+func Attribute(targets []Target, opts options.Options) []Footprint {
+	f := newFinder(opts)
+	a := newAttributor(f, targets)
+
+	searchPaths := f.AllSearchPaths()
+	if opts.BundleOnly {
+		searchPaths = f.ApplicationRoots()
+	}
+
+	footprints := make([]Footprint, len(targets))
+	for i, target := range targets {
+		footprints[i].Target = target
+	}
+	if len(targets) == 0 {
+		return footprints
+	}
+
+	var wg sync.WaitGroup
+	attributedChan := make(chan attributed, 100)
+	for _, rootPath := range searchPaths {
+		wg.Add(1)
+		go func(rootPath string) {
+			defer wg.Done()
+			emit := func(match *Match, owner int) {
+				if match.Confidence >= opts.MinConfidence {
+					attributedChan <- attributed{match: match, owner: owner}
+				}
+			}
+			if f.isApplicationRoot(rootPath) {
+				a.attributeApps(rootPath, emit)
+			} else {
+				a.attributeFiles(rootPath, emit)
+			}
+		}(rootPath)
+	}
+
+	go func() {
+		wg.Wait()
+		close(attributedChan)
+	}()
+
+	for result := range attributedChan {
+		footprints[result.owner].Matches = append(footprints[result.owner].Matches, result.match)
+	}
+
+	// Packages also install files outside the searched folders
+	if !opts.BundleOnly {
+		a.attributeReceipts(f.systemReceipts(opts), footprints, opts)
+	}
+
+	for _, footprint := range footprints {
+		sort.Slice(footprint.Matches, func(i, j int) bool { return footprint.Matches[i].Path < footprint.Matches[j].Path })
+	}
+	return footprints
+}
+
+// Builds the automatons over every target's identifiers and app name
+func newAttributor(f Finder, targets []Target) *attributor {
+	a := &attributor{
+		finder:  f,
+		targets: targets,
+		ids:     newAutomaton[byte](),
+		names:   newAutomaton[string](),
+		bundles: map[string]int{},
+		byID:    map[string]int{},
+	}
+
+	for i, target := range targets {
+		if target.BundlePath != "" {
+			a.bundles[target.BundlePath] = i
+		}
+		bundleID := strings.ToLower(target.BundleID)
+		if bundleID != "" {
+			if _, ok := a.byID[bundleID]; !ok {
+				a.byID[bundleID] = i
+			}
+			a.ids.add([]byte(bundleID), i)
+			a.ids.add([]byte(strings.TrimRightFunc(bundleID, unicode.IsDigit)), i)
+		}
+		// Every identifier rule requires the name to contain the identifier
+		for _, id := range target.Identifiers {
+			a.ids.add([]byte(strings.ToLower(id.Value)), i)
+		}
+		a.names.add(tokenize(strings.ToLower(target.AppName)), i)
+	}
+
+	a.ids.build()
+	a.names.build()
+	return a
+}
+
+// Returns the targets whose identifiers or app name occur in the name, in target order
+func (a *attributor) candidates(name string) []int {
+	lower := strings.ToLower(name)
+	seen := map[int]bool{}
+	a.ids.search([]byte(lower), func(i int) { seen[i] = true })
+	a.names.search(tokenize(strings.TrimRightFunc(lower, unicode.IsDigit)), func(i int) { seen[i] = true })
+
+	candidates := make([]int, 0, len(seen))
+	for i := range seen {
+		candidates = append(candidates, i)
+	}
+	sort.Ints(candidates)
+	return candidates
+}
+
+// Confirms the candidates with the matcher rules and returns the best match
+// and its target, preferring higher confidence and then longer identifiers
+func (a *attributor) attribute(path, name string, kind Kind, contexts func(int) ScanContext) (*Match, int) {
+	return a.attributeOwned(path, name, "", kind, contexts)
+}
+
+// Attributes an entry by the owner its container metadata declares, or by
+// name when declared is ""
+func (a *attributor) attributeOwned(path, name, declared string, kind Kind, contexts func(int) ScanContext) (*Match, int) {
+	if declared != "" {
+		name = declared
+	}
+	var best *Match
+	owner := -1
+	for _, i := range a.candidates(name) {
+		ctx := contexts(i)
+		id, rule, ok := a.finder.matchIdentifier(name, ctx)
+		if !ok {
+			continue
+		}
+		match := ctx.newMatch(path, kind, id, rule)
+		if declared != "" {
+			match = ctx.newContainerMatch(path, declared, id, rule)
+		}
+		if best == nil || match.Confidence > best.Confidence ||
+			(match.Confidence == best.Confidence && len(match.Identifier.Value) > len(best.Identifier.Value)) {
+			best, owner = match, i
+		}
+	}
+	return best, owner
+}
+
+// Returns a lazily filled cache of the scan context of every target for the root
+func (a *attributor) contexts(rootPath string) func(int) ScanContext {
+	cache := map[int]ScanContext{}
+	return func(i int) ScanContext {
+		ctx, ok := cache[i]
+		if !ok {
+			ctx = a.finder.newScanContext(a.targets[i], rootPath, nil)
+			cache[i] = ctx
+		}
+		return ctx
+	}
+}
+
+// Attributes the bundles discovered below an application root.
+//
+// A bundle belongs to the target discovered at its path, falling back to
+// the target declaring its bundle ID and then to the name based rules
+func (a *attributor) attributeApps(rootPath string, emit func(*Match, int)) {
+	contexts := a.contexts(rootPath)
+	for _, bundle := range DiscoverBundles([]string{rootPath}, a.finder.AppDepth) {
+		owner, ok := a.bundles[bundle.Path]
+		if !ok && bundle.Info.Identifier != "" {
+			owner, ok = a.byID[strings.ToLower(bundle.Info.Identifier)]
+		}
+		if ok {
+			ctx := contexts(owner)
+			id := Identifier{Value: a.targets[owner].BundleID, Source: SourceBundleID}
+			emit(ctx.newMatch(bundle.Path, KindBundle, id, RuleBundleInfo), owner)
+			continue
+		}
+		if match, owner := a.attribute(bundle.Path, filepath.Base(bundle.Path), KindBundle, contexts); match != nil {
+			emit(match, owner)
+		}
+	}
+}
+
+// Walks a data root once and attributes every entry.
+//
+// The walk descends every directory up to the root's search depth. Unlike
+// FindAppFiles it never skips a directory by the domain hint, as that skip
+// depends on the target and the directory may still hold another app's files
+func (a *attributor) attributeFiles(rootPath string, emit func(*Match, int)) {
+	contexts := a.contexts(rootPath)
+	searchDepth := a.finder.searchDepth(rootPath)
+
+	err := index.WalkDir(a.finder.dirs(), rootPath, func(subPath string, d fs.DirEntry, err error) error {
+		if subPath == rootPath || err != nil {
+			return nil
+		}
+
+		kind := kindOf(d.Type())
+		if !d.Type().IsRegular() && kind == KindFile {
+			return nil // devices, sockets and pipes are never matched
+		}
+		if kind == KindDir && a.finder.isSearchRoot(subPath) {
+			return fs.SkipDir // scanned as a root of its own
+		}
+		depth := 0
+		if relPath, err := filepath.Rel(rootPath, subPath); err == nil {
+			depth = len(strings.Split(relPath, string(os.PathSeparator)))
+		}
+
+		// Containers declare their owner in metadata which beats the folder name
+		declared := ""
+		if kind == KindDir {
+			declared = a.finder.containerOwner(subPath, rootPath, depth)
+		}
+		match, owner := a.attributeOwned(subPath, d.Name(), declared, kind, contexts)
+		if match == nil && kind == KindFile && launchd.IsJobPath(subPath) {
+			match, owner = a.attributeJob(subPath, contexts)
+		}
+		if match != nil {
+			emit(match, owner)
+			if kind == KindDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if kind == KindDir && (declared != "" || depth == 0 || depth > searchDepth) {
+			return fs.SkipDir
+		}
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintln(prompt.Output, "[rmapp] Error on path:", rootPath, err)
+	}
+}
+
+// Attributes a launchd job plist to the first target whose bundle runs the job
+func (a *attributor) attributeJob(path string, contexts func(int) ScanContext) (*Match, int) {
+	job, err := launchd.ReadJob(path)
+	if err != nil {
+		return nil, -1
+	}
+	for i, target := range a.targets {
+		if job.RunsFrom(target.BundlePath, target.BundleID) {
+			id := Identifier{Value: job.Label, Source: SourceLaunchJob}
+			return contexts(i).newMatch(path, KindFile, id, RuleLaunchJob), i
+		}
+	}
+	return nil, -1
+}
+
+// Attributes the files of every package to the target it most likely
+// installed, scored the same way as MatchReceipts.
+//
+// Files already attributed by name are left to the app they went to
+func (a *attributor) attributeReceipts(receipts []Receipt, footprints []Footprint, opts options.Options) {
+	if len(receipts) == 0 {
+		return
+	}
+	contexts := a.contexts(a.finder.Darwin.System.SystemReceipts)
+
+	var attributed []string
+	for _, footprint := range footprints {
+		attributed = append(attributed, Paths(footprint.Matches)...)
+	}
+
+	for _, receipt := range receipts {
+		files, err := receipt.Files()
+		if err != nil {
+			continue
+		}
+
+		// The package belongs to the app whose bundle it lists, or else to
+		// the app its identifier matches best
+		candidates := a.candidates(receipt.PackageID)
+		for _, file := range files {
+			if i, ok := a.bundles[file]; ok {
+				candidates = append(candidates, i)
+			}
+		}
+		owner, best := -1, 0.0
+		for _, i := range candidates {
+			if confidence, ok := a.finder.receiptConfidence(receipt, files, contexts(i)); ok && (owner < 0 || confidence > best) {
+				owner, best = i, confidence
+			}
+		}
+		if owner < 0 {
+			continue
+		}
+
+		var others []string
+		for path, i := range a.bundles {
+			if i != owner {
+				others = append(others, path)
+			}
+		}
+		for _, match := range a.finder.receiptMatches(receipt, files, best, a.targets[owner].BundlePath, others) {
+			if match.Confidence >= opts.MinConfidence && !coveredBy(match.Path, attributed) {
+				footprints[owner].Matches = append(footprints[owner].Matches, match)
+				attributed = append(attributed, match.Path)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package finder
+
+// automaton is an Aho-Corasick automaton finding every added pattern
+// occurring in a sequence of symbols in a single pass over the sequence.
+//
+// Used with bytes to find identifiers inside names and with
+// tokens to find tokenized app names inside tokenized names
+type automaton[S comparable] struct {
+	next    []map[S]int // goto transitions of each state
+	fail    []int       // state to continue from when no transition matches
+	outputs [][]int     // values of the patterns ending in each state
+}
+
+// Creates an automaton holding only the root state
+func newAutomaton[S comparable]() *automaton[S] {
+	return &automaton[S]{
+		next:    []map[S]int{{}},
+		fail:    []int{0},
+		outputs: [][]int{nil},
+	}
+}
+
+// Adds a pattern reported as value when found. Empty patterns are ignored
+func (a *automaton[S]) add(pattern []S, value int) {
+	if len(pattern) == 0 {
+		return
+	}
+	state := 0
+	for _, symbol := range pattern {
+		to, ok := a.next[state][symbol]
+		if !ok {
+			to = len(a.next)
+			a.next = append(a.next, map[S]int{})
+			a.fail = append(a.fail, 0)
+			a.outputs = append(a.outputs, nil)
+			a.next[state][symbol] = to
+		}
+		state = to
+	}
+	a.outputs[state] = append(a.outputs[state], value)
+}
+
+// Computes the failure links breadth first once every pattern was added.
+//
+// Each state also inherits the outputs of its failure state so patterns
+// that are suffixes of longer patterns are reported as well
+func (a *automaton[S]) build() {
+	var queue []int
+	for _, to := range a.next[0] {
+		a.fail[to] = 0
+		queue = append(queue, to)
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for symbol, to := range a.next[state] {
+			fail := a.fail[state]
+			for {
+				if candidate, ok := a.next[fail][symbol]; ok {
+					a.fail[to] = candidate
+					break
+				}
+				if fail == 0 {
+					a.fail[to] = 0
+					break
+				}
+				fail = a.fail[fail]
+			}
+			a.outputs[to] = append(a.outputs[to], a.outputs[a.fail[to]]...)
+			queue = append(queue, to)
+		}
+	}
+}
+
+// Reports the value of every pattern occurring in text
+func (a *automaton[S]) search(text []S, found func(value int)) {
+	state := 0
+	for _, symbol := range text {
+		for {
+			if to, ok := a.next[state][symbol]; ok {
+				state = to
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = a.fail[state]
+		}
+		for _, value := range a.outputs[state] {
+			found(value)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package finder
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Confidence thresholds
+const (
+	HIGH_CONFIDENCE float64 = 0.8 // matches at or above are almost certainly the app's
+	LOW_CONFIDENCE  float64 = 0.5 // matches below are only removed after explicit confirmation
+)
+
+// Scores how likely a matched entry belongs to the app based on the rule
+// that matched and how much of the entry's name the identifier covers.
+//
+// Names equal to an identifier score higher than names merely containing it,
+// and token hits inside longer names score lowest as they produce most false positives
+func scoreMatch(name string, rule Rule, id Identifier, ctx ScanContext) float64 {
+	lower := strings.ToLower(name)
+	stem := trimExtensions(lower)
+	value := strings.ToLower(id.Value)
+
+	switch rule {
+	case RuleBundleInfo, RuleReceipt, RuleLaunchJob:
+		return 1.0
+	case RuleBundleID:
+		if stem == value || lower == value {
+			return 1.0
+		}
+		return 0.9
+	case RuleIdentifier:
+		switch {
+		// Vendor groups are used by the vendor's other apps as well
+		case id.Shared:
+			return 0.3
+		// Executable names like "Updater" or "Helper" are shared by many apps
+		case id.Source == SourceExecutable:
+			return 0.4
+		case id.Exact || stem == value || lower == value:
+			return 0.9
+		default:
+			return 0.8
+		}
+	case RuleBundleIDBase:
+		return 0.6
+	case RuleToken:
+		score := 0.3
+		// Name consists of nothing but the app name ("Slack", "Slack.log")
+		if strings.Join(tokenize(strings.TrimRightFunc(stem, unicode.IsDigit)), " ") == strings.Join(ctx.TokenizedApp, " ") {
+			score = 0.6
+		}
+		// Name also carries the vendor from the bundle ID
+		if ctx.DomainHint != "" && strings.Contains(lower, strings.ToLower(ctx.DomainHint)) {
+			score += 0.1
+		}
+		return score
+	}
+	return 0
+}
+
+// ConfidenceLabel describes a confidence score for display
+func ConfidenceLabel(confidence float64) string {
+	switch {
+	case confidence >= HIGH_CONFIDENCE:
+		return "high"
+	case confidence >= LOW_CONFIDENCE:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// FilterByConfidence drops matches scoring below min
+func FilterByConfidence(matches []*Match, min float64) []*Match {
+	if min <= 0 {
+		return matches
+	}
+	var kept []*Match
+	for _, match := range matches {
+		if match.Confidence >= min {
+			kept = append(kept, match)
+		}
+	}
+	return kept
+}
+
+// Strips common data file extensions so "com.vendor.app.plist" compares as "com.vendor.app"
+func trimExtensions(name string) string {
+	for _, ext := range []string{".plist", ".savedstate", ".binarycookies", ".log", ".app"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
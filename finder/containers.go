@@ -0,0 +1,47 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/alewtschuk/rmapp/plist"
+)
+
+// Name of the metadata file containermanagerd writes at the root of every container
+const ContainerMetadataFile = ".com.apple.containermanagerd.metadata.plist"
+
+// ContainerIdentifier returns the owner identifier (MCMMetadataIdentifier)
+// declared by the container at dir, or "" if it has no readable metadata
+func ContainerIdentifier(dir string) string {
+	dict, err := plist.ReadDict(filepath.Join(dir, ContainerMetadataFile))
+	if err != nil {
+		return ""
+	}
+	return plist.String(dict, "MCMMetadataIdentifier")
+}
+
+// Checks if root holds sandbox containers named by ID or UUID
+func (f Finder) isContainerRoot(root string) bool {
+	return root == f.Darwin.UserPaths.ContainersPath || root == f.Darwin.UserPaths.GroupContainers
+}
+
+// Returns the owner declared by the container at path if it is a container
+// directly inside a container root, otherwise ""
+func (f Finder) containerOwner(path, root string, depth int) string {
+	if depth != 1 || !f.isContainerRoot(root) {
+		return ""
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return ""
+	}
+	return ContainerIdentifier(path)
+}
+
+// Creates a match for a container matched through its declared owner,
+// scoring the owner identifier instead of the folder name
+func (ctx ScanContext) newContainerMatch(path, owner string, id Identifier, rule Rule) *Match {
+	match := ctx.newMatch(path, KindDir, id, rule)
+	match.Owner = owner
+	match.Confidence = scoreMatch(owner, rule, id, ctx)
+	return match
+}
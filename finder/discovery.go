@@ -0,0 +1,166 @@
+package finder
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/desktop"
+	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/plist"
+)
+
+// Extensions of package directories that discovery never descends into
+var packageExtensions = []string{".app", ".framework", ".bundle", ".plugin", ".appex", ".xpc", ".kext"}
+
+// Bundle describes an application bundle or desktop entry found on disk
+type Bundle struct {
+	Path string           // full path to the .app bundle or .desktop file
+	Root string           // application root the bundle was discovered under
+	Info plist.BundleInfo // metadata read from the bundle's Info.plist or desktop entry
+}
+
+// Name returns the bundle's file name without the .app extension, or
+// the name a desktop entry is listed under
+func (b Bundle) Name() string {
+	if desktop.IsEntryPath(b.Path) && b.Info.Name != "" {
+		return b.Info.Name
+	}
+	return b.fileName()
+}
+
+// Returns the file name without the .app or .desktop extension
+func (b Bundle) fileName() string {
+	return strings.TrimSuffix(strings.TrimSuffix(filepath.Base(b.Path), ".app"), desktop.EXTENSION)
+}
+
+// ReadBundleInfo reads the metadata of an .app bundle or a desktop entry.
+//
+// Desktop entries carry their app ID as the identifier when it is a
+// reverse-DNS name, as shorter IDs would match unrelated files
+func ReadBundleInfo(path string) (plist.BundleInfo, error) {
+	if !desktop.IsEntryPath(path) {
+		return plist.ReadBundleInfo(path)
+	}
+	entry, err := desktop.ReadFile(path)
+	return entryInfo(entry), err
+}
+
+// Maps a desktop entry onto the bundle metadata the matcher works with
+func entryInfo(entry desktop.Entry) plist.BundleInfo {
+	info := plist.BundleInfo{Name: entry.Name, Executable: entry.Executable()}
+	if IsBundleID(entry.AppID()) {
+		info.Identifier = entry.AppID()
+	}
+	return info
+}
+
+// ApplicationRoots returns every directory searched for app bundles
+func (f Finder) ApplicationRoots() []string {
+	return f.Provider.ApplicationRoots()
+}
+
+// Checks if rootPath is one of the directories holding .app bundles
+func (f Finder) isApplicationRoot(rootPath string) bool {
+	for _, root := range f.ApplicationRoots() {
+		if root == rootPath {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverBundles walks each root for .app bundles and desktop entries.
+//
+// Ordinary folders (Utilities, vendor folders, web app folders) are descended
+// into until bundles would be deeper than depth path segments below the root.
+// Bundles are never descended into so helper apps are not reported. Desktop
+// entries hidden by the user are skipped
+func DiscoverBundles(roots []string, depth int) []Bundle {
+	if depth < 1 {
+		depth = DISCOVERY_DEPTH
+	}
+
+	var bundles []Bundle
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || path == root {
+				return nil
+			}
+			if desktop.IsEntryPath(path) && !d.IsDir() {
+				if entry, err := desktop.ReadFile(path); err == nil && !entry.Hidden {
+					bundles = append(bundles, Bundle{Path: path, Root: root, Info: entryInfo(entry)})
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				return nil
+			}
+
+			name := d.Name()
+			if strings.HasSuffix(name, ".app") {
+				info, _ := plist.ReadBundleInfo(path)
+				bundles = append(bundles, Bundle{Path: path, Root: root, Info: info})
+				return fs.SkipDir
+			}
+			if isPackage(name) || strings.HasPrefix(name, ".") {
+				return fs.SkipDir
+			}
+
+			// A folder at depth can only hold bundles deeper than allowed
+			rel, err := filepath.Rel(root, path)
+			if err != nil || len(strings.Split(rel, string(os.PathSeparator))) >= depth {
+				return fs.SkipDir
+			}
+			return nil
+		})
+	}
+
+	sort.SliceStable(bundles, func(i, j int) bool {
+		return bundles[i].Path < bundles[j].Path
+	})
+	return bundles
+}
+
+// DiscoverInstalled returns every bundle under the application roots
+func DiscoverInstalled(opts options.Options) []Bundle {
+	f := newFinder(opts)
+	return DiscoverBundles(f.ApplicationRoots(), f.AppDepth)
+}
+
+// BundlesWithID returns every copy of the bundle with the given identifier
+func BundlesWithID(bundles []Bundle, bundleID string) []Bundle {
+	var copies []Bundle
+	for _, bundle := range bundles {
+		if bundleID != "" && strings.EqualFold(bundle.Info.Identifier, bundleID) {
+			copies = append(copies, bundle)
+		}
+	}
+	return copies
+}
+
+// BundlesNamed returns the bundles whose file name is appName.app, or
+// desktop entries with appName as their ID or name
+func BundlesNamed(bundles []Bundle, appName string) []Bundle {
+	var found []Bundle
+	appName = strings.TrimSuffix(appName, ".app")
+	for _, bundle := range bundles {
+		if strings.EqualFold(bundle.fileName(), appName) || strings.EqualFold(bundle.Name(), appName) {
+			found = append(found, bundle)
+		}
+	}
+	return found
+}
+
+// Checks if a directory name carries a package extension
+func isPackage(name string) bool {
+	ext := filepath.Ext(name)
+	for _, pkg := range packageExtensions {
+		if strings.EqualFold(ext, pkg) {
+			return true
+		}
+	}
+	return false
+}
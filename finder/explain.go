@@ -0,0 +1,246 @@
+package finder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/plist"
+)
+
+// Outcomes of a traced matcher decision
+const (
+	OutcomeMatched    = "matched"
+	OutcomeNotMatched = "not matched"
+	OutcomeSkipped    = "skipped"
+	OutcomeDescended  = "descended"
+	OutcomeDropped    = "dropped"
+	OutcomeOutside    = "outside"
+	OutcomeMissing    = "missing"
+)
+
+// Trace is a single decision the matcher took while walking towards a path
+type Trace struct {
+	Path    string   // entry the decision was taken on
+	Outcome string   // one of the Outcome constants
+	Detail  string   // why the decision was taken
+	Checks  []string // result of every rule when nothing matched
+}
+
+// Explanation describes why a path was or was not matched for a target
+type Explanation struct {
+	Path     string  // path being explained
+	Root     string  // search root holding the path, empty if outside all roots
+	Category string  // label of the search root
+	Matched  bool    // true if a scan would flag the path
+	Match    *Match  // match covering the path when matched
+	Trace    []Trace // decisions from the root down to the path
+}
+
+// Explain replays the scan for a single path and traces every decision the
+// matcher takes from the search root down to it.
+//
+// Uses the same matchIdentifier and skipReason logic as FindMatches so the
+// result reflects exactly what a scan would do
+func Explain(target Target, path string, opts options.Options) Explanation {
+	f := newFinder(opts)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	exp := Explanation{Path: path}
+
+	roots := f.AllSearchPaths()
+	if opts.BundleOnly {
+		roots = f.ApplicationRoots()
+	}
+	exp.Root = rootOf(path, roots)
+	if exp.Root == "" {
+		exp.trace(path, OutcomeOutside, fmt.Sprintf("not inside any of the %d search roots", len(roots)))
+		return exp
+	}
+	exp.Category = f.Category(exp.Root)
+
+	ctx := f.newScanContext(target, exp.Root, nil)
+	if f.isApplicationRoot(exp.Root) {
+		f.explainApp(&exp, ctx, opts)
+	} else {
+		f.explainFiles(&exp, ctx, opts)
+	}
+	return exp
+}
+
+// Walks the path components below a data root the same way FindAppFiles does
+func (f Finder) explainFiles(exp *Explanation, ctx ScanContext, opts options.Options) {
+	segments := strings.Split(relPath(exp.Root, exp.Path), string(os.PathSeparator))
+	current := exp.Root
+	missing := false
+
+	for i, name := range segments {
+		current = filepath.Join(current, name)
+		depth := i + 1
+		last := i == len(segments)-1
+
+		kind := KindDir
+		info, err := os.Lstat(current)
+		switch {
+		case err == nil:
+			kind = kindOf(info.Mode())
+		case last:
+			kind = KindFile
+		}
+		if err != nil && !missing {
+			missing = true
+			exp.trace(current, OutcomeMissing, "does not exist on disk so a scan never reaches it, tracing as if it did")
+		}
+
+		// Containers declare their owner in metadata which beats the folder name
+		if owner := f.containerOwner(current, exp.Root, depth); owner != "" {
+			if id, rule, ok := f.matchIdentifier(owner, ctx); ok {
+				f.explainMatch(exp, ctx.newContainerMatch(current, owner, id, rule), last, opts)
+				return
+			}
+			exp.Trace = append(exp.Trace, Trace{Path: current, Outcome: OutcomeNotMatched, Detail: fmt.Sprintf("container metadata declares owner %q", owner), Checks: f.ruleChecks(owner, ctx)})
+			exp.trace(current, OutcomeSkipped, "container belongs to another app")
+			return
+		}
+
+		id, rule, ok := f.matchIdentifier(name, ctx)
+		if kind == KindFile {
+			id, rule, ok = f.matchFile(current, ctx)
+		}
+		if ok {
+			f.explainMatch(exp, ctx.newMatch(current, kind, id, rule), last, opts)
+			return
+		}
+		exp.Trace = append(exp.Trace, Trace{Path: current, Outcome: OutcomeNotMatched, Detail: "no rule matched " + fmt.Sprintf("%q", name), Checks: f.ruleChecks(name, ctx)})
+
+		if kind != KindDir {
+			return
+		}
+		switch f.skipReason(name, depth, ctx) {
+		case SkipDepthLimit:
+			exp.trace(current, OutcomeSkipped, fmt.Sprintf("%s: directory depth %d exceeds search depth %d of %s", SkipDepthLimit, depth, ctx.SearchDepth, exp.Category))
+			return
+		case SkipDomainHint:
+			exp.trace(current, OutcomeSkipped, fmt.Sprintf("%s: name contains vendor %q but does not match the app", SkipDomainHint, ctx.DomainHint))
+			return
+		}
+		if !last {
+			exp.trace(current, OutcomeDescended, fmt.Sprintf("directory at depth %d is within search depth %d", depth, ctx.SearchDepth))
+		}
+	}
+}
+
+// Finds the bundle holding the path below an application root the same way FindApp does
+func (f Finder) explainApp(exp *Explanation, ctx ScanContext, opts options.Options) {
+	segments := strings.Split(relPath(exp.Root, exp.Path), string(os.PathSeparator))
+	current := exp.Root
+
+	for i, name := range segments {
+		current = filepath.Join(current, name)
+		depth := i + 1
+
+		if strings.HasSuffix(name, ".app") {
+			if depth > f.AppDepth {
+				exp.trace(current, OutcomeSkipped, fmt.Sprintf("%s: bundle is %d folders deep, discovery stops at %d", SkipDepthLimit, depth, f.AppDepth))
+				return
+			}
+
+			bundle := Bundle{Path: current, Root: exp.Root}
+			if info, err := plist.ReadBundleInfo(current); err == nil {
+				bundle.Info = info
+			}
+			last := current == exp.Path
+			if ctx.BundleID != "" && strings.EqualFold(bundle.Info.Identifier, ctx.BundleID) {
+				id := Identifier{Value: ctx.BundleID, Source: SourceBundleID}
+				f.explainMatch(exp, ctx.newMatch(current, KindBundle, id, RuleBundleInfo), last, opts)
+				return
+			}
+			if id, rule, ok := f.matchIdentifier(name, ctx); ok {
+				f.explainMatch(exp, ctx.newMatch(current, KindBundle, id, rule), last, opts)
+				return
+			}
+			checks := f.ruleChecks(name, ctx)
+			checks = append(checks, fmt.Sprintf("%s: Info.plist declares %q", RuleBundleInfo, bundle.Info.Identifier))
+			exp.Trace = append(exp.Trace, Trace{Path: current, Outcome: OutcomeNotMatched, Detail: "bundle does not belong to the app", Checks: checks})
+			return
+		}
+
+		if isPackage(name) || strings.HasPrefix(name, ".") {
+			exp.trace(current, OutcomeSkipped, "discovery does not descend into packages or hidden folders")
+			return
+		}
+		exp.trace(current, OutcomeDescended, "ordinary folder searched for bundles")
+	}
+
+	exp.trace(exp.Path, OutcomeNotMatched, "only .app bundles are matched inside application folders")
+}
+
+// Records a match, applying the confidence threshold a scan would apply
+func (f Finder) explainMatch(exp *Explanation, match *Match, last bool, opts options.Options) {
+	detail := fmt.Sprintf("%s (%s %q), confidence %.0f%%", match.Rule, match.Identifier.Source, match.Identifier.Value, match.Confidence*100)
+	if match.Confidence < opts.MinConfidence {
+		exp.trace(match.Path, OutcomeDropped, detail+fmt.Sprintf(" is below --min-confidence %.0f%%", opts.MinConfidence*100))
+		return
+	}
+	if !last {
+		detail += ", everything inside is removed with it"
+	}
+	exp.trace(match.Path, OutcomeMatched, detail)
+	exp.Matched = true
+	exp.Match = match
+}
+
+// Describes the result of every matcher rule for a name that did not match
+func (f Finder) ruleChecks(name string, ctx ScanContext) []string {
+	lower := strings.ToLower(name)
+	bundleID := strings.ToLower(ctx.BundleID)
+	var checks []string
+
+	if bundleID == "" {
+		checks = append(checks, fmt.Sprintf("%s: no bundle ID known", RuleBundleID))
+	} else {
+		checks = append(checks, fmt.Sprintf("%s: %q not found in name", RuleBundleID, bundleID))
+		if base := strings.TrimRightFunc(bundleID, unicode.IsDigit); base != bundleID {
+			checks = append(checks, fmt.Sprintf("%s: %q not found in name", RuleBundleIDBase, base))
+		} else {
+			checks = append(checks, fmt.Sprintf("%s: bundle ID has no numeric suffix", RuleBundleIDBase))
+		}
+	}
+
+	checks = append(checks, fmt.Sprintf("%s: none of %d identifiers match", RuleIdentifier, len(ctx.Identifiers)))
+	checks = append(checks, fmt.Sprintf("%s: %v not found in order in %v", RuleToken, ctx.TokenizedApp,
+		tokenize(strings.TrimRightFunc(lower, unicode.IsDigit))))
+	return checks
+}
+
+// Appends a decision without rule checks
+func (exp *Explanation) trace(path, outcome, detail string) {
+	exp.Trace = append(exp.Trace, Trace{Path: path, Outcome: outcome, Detail: detail})
+}
+
+// Returns the most specific root containing path
+func rootOf(path string, roots []string) string {
+	best := ""
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		if strings.HasPrefix(path, root+string(os.PathSeparator)) && len(root) > len(best) {
+			best = root
+		}
+	}
+	return best
+}
+
+// Returns path relative to root, assuming root contains it
+func relPath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
@@ -2,27 +2,33 @@ package finder
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"sync"
 
+	"github.com/alewtschuk/rmapp/index"
 	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/prompt"
 )
 
 // Declare constants
 const (
 	STANDARD_DEPTH    int = 1
 	PREFERENCES_DEPTH int = 2
+	DISCOVERY_DEPTH   int = 3 // max path segments between an application root and a bundle
 )
 
 // ScanContext encapsulates all info needed during directory walking
 type ScanContext struct {
 	AppName     string
 	BundleID    string
+	BundlePath  string
+	Identifiers []Identifier
 	DomainHint  string
 	SearchDepth int
-	MatchesChan chan string
+	MatchesChan chan *Match
 	RootPath    string
+	Category    string
+	Index       *index.Index // scan index sizes are read from, nil when disabled
 
 	//KMP Additions
 	TokenizedApp []string
@@ -31,18 +37,23 @@ type ScanContext struct {
 
 // Whole Finder struct that holds everything related to finder
 type Finder struct {
-	OSMain       OSMainPaths
-	System       SystemPaths
-	UserPaths    UserPaths
-	MatchedPaths []string
-	Verbosity    bool
-	Reported     bool
+	Darwin    DarwinPaths
+	Linux     LinuxPaths
+	Platform  string       // platform whose layout is searched
+	Provider  PathProvider // layout of the platform, Darwin or Linux
+	Matches   []*Match
+	Verbosity bool
+	Reported  bool
+	AppDepth  int             // how deep application roots are searched for bundles
+	Index     *index.Index    // persistent scan index, nil when disabled with --no-cache
+	roots     map[string]bool // search roots, skipped when nested inside another
 }
 
 // The default os directories where the .app file should exist
 type OSMainPaths struct {
 	RootApplicationsPath string // default os applications path
 	UserApplicationsPath string // default user applications path
+	VolumesPath          string // mount point of external volumes holding their own Applications
 }
 
 // Directories where the system wide paths is stored
@@ -83,45 +94,53 @@ type UserPaths struct {
 
 // Creates and loads a new Finder with all needed fields
 func NewFinder(appName string, bundleID string, opts options.Options) Finder {
+	return NewTargetFinder(Target{AppName: appName, BundleID: bundleID}, opts)
+}
+
+// Creates and loads a new Finder searching for every identifier of the target
+func NewTargetFinder(target Target, opts options.Options) Finder {
+	finder := newFinder(opts)
+
+	matches, err := finder.FindMatches(target, opts)
+	if err != nil {
+		fmt.Fprintln(prompt.Output, "NewFinder Error: ", err)
+	}
+	finder.Matches = matches
+	return finder
+}
+
+// Paths returns the paths of all matches
+func (f Finder) Paths() []string {
+	return Paths(f.Matches)
+}
+
+// Creates a Finder with all search paths populated but without scanning
+func newFinder(opts options.Options) Finder {
 	// Extract home directory for use in user identification if ran as sudo
-	home := os.Getenv("HOME")
+	home := HomeDir(opts)
 	finder := Finder{
-		OSMain: OSMainPaths{
-			RootApplicationsPath: "/Applications",
-			UserApplicationsPath: fmt.Sprintf("%s/Applications", home),
-		},
-		System: SystemPaths{
-			SystemSupportFilesPath:      "/Library/Application Support",
-			SystemCrashReports:          "/Library/Application Support/CrashReporter",
-			SystemCaches:                "/Library/Caches",
-			SystemExtensions:            "/Library/Extensions",
-			SystemInternetPlugIns:       "/Library/Internet Plug-Ins",
-			SystemLaunchAgents:          "/Library/LaunchAgents",
-			SystemLaunchDaemons:         "/Library/LaunchDaemons",
-			SystemLogs:                  "/Library/Logs",
-			SystemPrivilegedHelperTools: "/Library/PrivilegedHelperTools",
-			SystemReceipts:              "/var/db/receipts",
-			SystemBin:                   "/usr/local/bin",
-			SystemOpt:                   "/usr/local/opt",
-			SystemSbin:                  "/usr/local/sbin",
-			SystemShare:                 "/usr/local/share",
-			SystemVar:                   "/usr/local/var",
-		},
-		UserPaths: UserPaths{
-			AppSupportFilesPath: fmt.Sprintf("%s/Library/Application Support", home),
-			PreferencesPath:     fmt.Sprintf("%s/Library/Preferences", home),
-			CachesPath:          fmt.Sprintf("%s/Library/Caches", home),
-			ContainersPath:      fmt.Sprintf("%s/Library/Containers", home),
-			SavedStatePath:      fmt.Sprintf("%s/Library/Saved Application State", home),
-			HTTPStorages:        fmt.Sprintf("%s/Library/HTTPStorages", home),
-			GroupContainers:     fmt.Sprintf("%s/Library/Group Containers", home),
-			InternetPlugIns:     fmt.Sprintf("%s/Library/Internet Plug-Ins", home),
-			LaunchAgents:        fmt.Sprintf("%s/Library/LaunchAgents", home),
-			Logs:                fmt.Sprintf("%s/Library/Logs", home),
-			WebKit:              fmt.Sprintf("%s/Library/WebKit", home),
-			ApplicationScripts:  fmt.Sprintf("%s/Library/Application Scripts", home),
-		},
+		Darwin:    NewDarwinPaths(opts.Root, home),
+		Linux:     NewLinuxPaths(opts.Root, home),
+		Platform:  Platform(opts),
 		Verbosity: opts.Verbosity,
+		AppDepth:  opts.AppDepth,
+	}
+
+	finder.Provider = finder.Darwin
+	if finder.Platform == LINUX {
+		finder.Provider = finder.Linux
+	}
+	finder.roots = map[string]bool{}
+	for _, root := range finder.AllSearchPaths() {
+		finder.roots[root] = true
+	}
+
+	if finder.AppDepth < 1 {
+		finder.AppDepth = DISCOVERY_DEPTH
+	}
+
+	if !opts.NoCache {
+		finder.Index = index.Default()
 	}
 
 	if opts.Peek || opts.Size {
@@ -130,68 +149,46 @@ func NewFinder(appName string, bundleID string, opts options.Options) Finder {
 		finder.Reported = false
 	}
 
-	matches, err := finder.FindMatches(appName, bundleID, opts)
-	if err != nil {
-		fmt.Println("NewFinder Error: ", err)
-	}
-	finder.MatchedPaths = matches
 	return finder
 }
 
 // Returns a string of all available paths to search
 func (f Finder) AllSearchPaths() []string {
-	return []string{
-		f.OSMain.RootApplicationsPath,
-		f.OSMain.UserApplicationsPath,
-		f.System.SystemSupportFilesPath,
-		f.System.SystemCrashReports,
-		f.System.SystemCaches,
-		f.System.SystemExtensions,
-		f.System.SystemInternetPlugIns,
-		f.System.SystemLaunchAgents,
-		f.System.SystemLaunchDaemons,
-		f.System.SystemLogs,
-		f.System.SystemPrivilegedHelperTools,
-		f.System.SystemReceipts,
-		f.System.SystemBin,
-		f.System.SystemOpt,
-		f.System.SystemSbin,
-		f.System.SystemShare,
-		f.System.SystemVar,
-		f.UserPaths.AppSupportFilesPath,
-		f.UserPaths.PreferencesPath,
-		f.UserPaths.CachesPath,
-		f.UserPaths.ContainersPath,
-		f.UserPaths.SavedStatePath,
-		f.UserPaths.HTTPStorages,
-		f.UserPaths.GroupContainers,
-		f.UserPaths.InternetPlugIns,
-		f.UserPaths.LaunchAgents,
-		f.UserPaths.Logs,
-		f.UserPaths.WebKit,
-		f.UserPaths.ApplicationScripts,
-	}
+	return f.Provider.SearchPaths()
+}
+
+// Category returns the display label of a search root
+func (f Finder) Category(root string) string {
+	return f.Provider.Category(root)
+}
+
+// Checks if path is a search root, which is scanned on its own
+// even when it lies inside another root
+func (f Finder) isSearchRoot(path string) bool {
+	return f.roots[path]
 }
 
 // Walks the filepath for each path available and checks if each path contains a match
-// to the bundleID or the appname.
+// to any identifier of the target or the appname.
 //
 // Internal WalkDir function passes matches to a channel which will be read from to
-// build a string slice of matched paths that will be flagged for deletion
-func (f *Finder) FindMatches(appName, bundleID string, opts options.Options) ([]string, error) {
+// build a slice of matched paths that will be flagged for deletion
+func (f *Finder) FindMatches(target Target, opts options.Options) ([]*Match, error) {
 	var (
-		err         error
-		matches     []string
-		searchDepth int
+		err     error
+		matches []*Match
 	)
-	tokenizedApp := tokenize(strings.ToLower(appName))
-	matchesChan := make(chan string)
+	matchesChan := make(chan *Match)
 	wg := sync.WaitGroup{}
 
+	// Bundles found in the application roots, which receipts leave out
+	var installed []Bundle
+	var installedMu sync.Mutex
+
 	searchPaths := f.AllSearchPaths()
 
-	if opts.BundleOnly { // if only the bundle is going to be removed only search the main directories
-		searchPaths = []string{f.OSMain.RootApplicationsPath, f.OSMain.UserApplicationsPath}
+	if opts.BundleOnly { // if only the bundle is going to be removed only search the application directories
+		searchPaths = f.ApplicationRoots()
 	}
 
 	for _, rootPath := range searchPaths {
@@ -199,27 +196,16 @@ func (f *Finder) FindMatches(appName, bundleID string, opts options.Options) ([]
 
 		go func(rootPath string) {
 			defer wg.Done()
-			if rootPath == f.UserPaths.PreferencesPath {
-				searchDepth = PREFERENCES_DEPTH
-			} else {
-				searchDepth = STANDARD_DEPTH
-			}
 
 			// Create context struct for passing context to other functions
-			ctx := ScanContext{
-				AppName:      appName,
-				BundleID:     bundleID,
-				DomainHint:   GetDomainHint(bundleID),
-				SearchDepth:  searchDepth,
-				MatchesChan:  matchesChan,
-				RootPath:     rootPath,
-				TokenizedApp: tokenizedApp,
-				LpsArray:     buildLPS(tokenizedApp),
-			}
+			ctx := f.newScanContext(target, rootPath, matchesChan)
 
 			// Check if root Applications directories hold the .app
-			if rootPath == f.OSMain.RootApplicationsPath || rootPath == f.OSMain.UserApplicationsPath {
-				f.FindApp(rootPath, ctx)
+			if f.isApplicationRoot(rootPath) {
+				bundles := f.FindApp(rootPath, ctx)
+				installedMu.Lock()
+				installed = append(installed, bundles...)
+				installedMu.Unlock()
 				return
 			}
 			f.FindAppFiles(rootPath, ctx, opts)
@@ -237,9 +223,48 @@ func (f *Finder) FindMatches(appName, bundleID string, opts options.Options) ([]
 		matches = append(matches, match)
 	}
 
-	if opts.Peek || opts.Size {
-		GenerateReport(matches, appName, opts)
+	// Packages also install files outside the searched folders
+	if !opts.BundleOnly {
+		matches = mergeMatches(matches, MatchReceipts(target, f.systemReceipts(opts), installed, opts))
+	}
+	matches = FilterByConfidence(matches, opts.MinConfidence)
+
+	if (opts.Peek || opts.Size) && opts.Output == "" {
+		GenerateReport(matches, target.AppName, opts)
 	}
 
 	return matches, err
 }
+
+// Creates the context for scanning rootPath for the target
+func (f Finder) newScanContext(target Target, rootPath string, matchesChan chan *Match) ScanContext {
+	tokenizedApp := tokenize(strings.ToLower(target.AppName))
+	return ScanContext{
+		AppName:      target.AppName,
+		BundleID:     target.BundleID,
+		BundlePath:   target.BundlePath,
+		Identifiers:  target.Identifiers,
+		DomainHint:   GetDomainHint(target.BundleID),
+		SearchDepth:  f.searchDepth(rootPath),
+		MatchesChan:  matchesChan,
+		RootPath:     rootPath,
+		Category:     f.Category(rootPath),
+		Index:        f.Index,
+		TokenizedApp: tokenizedApp,
+		LpsArray:     buildLPS(tokenizedApp),
+	}
+}
+
+// Returns how many folder levels below the root are searched
+func (f Finder) searchDepth(rootPath string) int {
+	return f.Provider.SearchDepth(rootPath)
+}
+
+// Returns the reader directories are walked with, going through
+// the scan index unless it is disabled
+func (f Finder) dirs() index.DirReader {
+	if f.Index == nil {
+		return index.OS
+	}
+	return f.Index
+}
@@ -38,3 +38,17 @@ func FormatSize(size int64) string {
 		return pfmt.ApplyColor(fmt.Sprintf("%d B", size), 205)
 	}
 }
+
+// FormatConfidence returns the confidence score as a fixed width,
+// colored percentage
+func FormatConfidence(confidence float64) string {
+	percent := fmt.Sprintf("%3.0f%%", confidence*100)
+	switch {
+	case confidence >= HIGH_CONFIDENCE:
+		return pfmt.ApplyColor(percent, 2)
+	case confidence >= LOW_CONFIDENCE:
+		return pfmt.ApplyColor(percent, 3)
+	default:
+		return pfmt.ApplyColor(percent, 9)
+	}
+}
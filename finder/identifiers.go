@@ -0,0 +1,115 @@
+package finder
+
+import (
+	"strings"
+)
+
+// Sources an Identifier can be derived from
+const (
+	SourceName       = "app name"
+	SourceBundleID   = "bundle id"
+	SourceLoginItem  = "login item"
+	SourceXPCService = "xpc service"
+	SourceHelper     = "privileged helper"
+	SourceExtension  = "app extension"
+	SourceExecutable = "executable"
+	SourceAppGroup   = "app group"
+	SourceKeychain   = "keychain group"
+	SourceReceipt    = "package receipt"
+	SourceLaunchJob  = "launchd job"
+)
+
+// Identifier is a name or ID an app may have stored its data under
+type Identifier struct {
+	Value  string // identifier as it appears on disk (e.g. com.vendor.app.helper)
+	Source string // where the identifier was derived from
+	Exact  bool   // only match names equal to Value, ignoring any extension
+	Shared bool   // also declared by another installed app, so its data may not be the target's alone
+}
+
+// Target describes everything known about the app being searched for
+type Target struct {
+	AppName     string       // name typed by the user, used for token matching
+	BundleID    string       // main bundle identifier, may be empty for leftovers
+	BundlePath  string       // path of the .app bundle, empty for leftovers
+	TeamID      string       // developer team ID from the code signature
+	Identifiers []Identifier // extra identifiers derived from the bundle's contents
+}
+
+// Suffixes exact identifiers may carry on disk
+var exactSuffixes = []string{".plist", ".savedstate"}
+
+// Checks if a single extra identifier matches the lowercased filename.
+//
+// Reverse-DNS identifiers match anywhere in the name like the bundle ID does.
+// Plain names such as executables are too generic for that and must equal the
+// filename ignoring any extension. Exact identifiers such as app groups must
+// equal the filename and only allow the suffixes of known data files
+func (id Identifier) matches(filename string) bool {
+	value := strings.ToLower(id.Value)
+	if value == "" {
+		return false
+	}
+	if filename == value {
+		return true
+	}
+	if id.Exact {
+		for _, suffix := range exactSuffixes {
+			if filename == value+suffix {
+				return true
+			}
+		}
+		return false
+	}
+	if IsBundleID(value) {
+		return strings.Contains(filename, value)
+	}
+	ext := strings.LastIndex(filename, ".")
+	return ext > 0 && filename[:ext] == value
+}
+
+// AddIdentifier appends id to ids unless an identifier with the same value is present
+func AddIdentifier(ids []Identifier, id Identifier) []Identifier {
+	if id.Value == "" {
+		return ids
+	}
+	for _, existing := range ids {
+		if strings.EqualFold(existing.Value, id.Value) {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+// GroupIdentifiers turns the app and keychain groups of a code signature into
+// exact identifiers.
+//
+// Group containers are named after the group itself, which on macOS usually
+// carries the team ID prefix. Groups without one also get a team prefixed variant
+func GroupIdentifiers(teamID string, appGroups, keychainGroups []string) []Identifier {
+	var ids []Identifier
+	for _, group := range appGroups {
+		ids = AddIdentifier(ids, Identifier{Value: group, Source: SourceAppGroup, Exact: true})
+		if teamID != "" && !strings.HasPrefix(group, teamID+".") {
+			ids = AddIdentifier(ids, Identifier{Value: teamID + "." + group, Source: SourceAppGroup, Exact: true})
+		}
+	}
+	for _, group := range keychainGroups {
+		ids = AddIdentifier(ids, Identifier{Value: group, Source: SourceKeychain, Exact: true})
+	}
+	return ids
+}
+
+// MarkShared flags the identifiers whose value is also among the
+// identifiers of other installed apps
+func MarkShared(ids, others []Identifier) []Identifier {
+	for i, id := range ids {
+		for _, other := range others {
+			if strings.EqualFold(id.Value, other.Value) {
+				ids[i].Shared = true
+				break
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,137 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/desktop"
+	"github.com/alewtschuk/rmapp/options"
+)
+
+// Matches reverse-DNS identifiers with at least three components (e.g. com.vendor.app)
+var bundleIDPattern = regexp.MustCompile(`^[A-Za-z0-9-]+(\.[A-Za-z0-9-]+){2,}$`)
+
+// Candidate is a bundle ID inferred from files left behind by an app
+type Candidate struct {
+	BundleID string   // inferred bundle identifier
+	Evidence []string // paths the identifier was inferred from
+}
+
+// InferBundleIDs looks through the user's data folders for bundle ID named data
+// belonging to appName and returns the candidates, best supported first.
+//
+// Used when the .app bundle no longer exists and the bundle ID must be
+// recovered from what the app left behind
+func InferBundleIDs(appName string, opts options.Options) []Candidate {
+	f := newFinder(opts)
+	evidence := map[string][]string{}
+
+	// Each root maps to the suffixes its bundle ID named entries carry
+	roots := map[string][]string{
+		f.Darwin.UserPaths.PreferencesPath:    {".plist"},
+		f.Darwin.UserPaths.ContainersPath:     {""},
+		f.Darwin.UserPaths.SavedStatePath:     {".savedState"},
+		f.Darwin.UserPaths.HTTPStorages:       {".binarycookies", ""},
+		f.Darwin.UserPaths.CachesPath:         {""},
+		f.Darwin.UserPaths.ApplicationScripts: {""},
+		f.Darwin.UserPaths.WebKit:             {""},
+	}
+	if f.Platform == LINUX {
+		roots = map[string][]string{
+			f.Linux.FlatpakData:      {""},
+			f.Linux.UserApplications: {desktop.EXTENSION},
+			f.Linux.Autostart:        {desktop.EXTENSION},
+			f.Linux.Config:           {""},
+			f.Linux.Data:             {""},
+			f.Linux.Cache:            {""},
+			f.Linux.State:            {""},
+		}
+	}
+
+	for root, suffixes := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(root, entry.Name())
+			id := trimAnySuffix(entry.Name(), suffixes)
+
+			// Containers declare their owner in metadata which beats the folder name
+			if root == f.Darwin.UserPaths.ContainersPath && entry.IsDir() {
+				if declared := ContainerIdentifier(path); declared != "" {
+					id = declared
+				}
+			}
+
+			if !looksLikeBundleID(id) || !nameMatchesID(appName, id) {
+				continue
+			}
+			evidence[id] = append(evidence[id], path)
+		}
+	}
+
+	var candidates []Candidate
+	for id, paths := range evidence {
+		sort.Strings(paths)
+		candidates = append(candidates, Candidate{BundleID: id, Evidence: paths})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i].Evidence) != len(candidates[j].Evidence) {
+			return len(candidates[i].Evidence) > len(candidates[j].Evidence)
+		}
+		return candidates[i].BundleID < candidates[j].BundleID
+	})
+	return candidates
+}
+
+// IsBundleID reports if s is shaped like a reverse-DNS bundle identifier
+func IsBundleID(s string) bool {
+	return bundleIDPattern.MatchString(s) && !strings.HasSuffix(s, ".app")
+}
+
+// Reports if s is shaped like a third party bundle identifier
+func looksLikeBundleID(s string) bool {
+	return IsBundleID(s) && !strings.HasPrefix(strings.ToLower(s), "com.apple.")
+}
+
+// Checks if a bundle ID plausibly belongs to the app name.
+//
+// Either the name's tokens appear in order in the ID, or a component of the
+// ID starts with the name squashed together ("Slack" to "slackmacgap")
+func nameMatchesID(appName, bundleID string) bool {
+	tokenizedApp := tokenize(strings.ToLower(appName))
+	if len(tokenizedApp) == 0 {
+		return false
+	}
+
+	ctx := ScanContext{TokenizedApp: tokenizedApp, LpsArray: buildLPS(tokenizedApp)}
+	id := strings.ToLower(bundleID)
+	if searchName(ctx, id) {
+		return true
+	}
+
+	squashed := strings.Join(tokenizedApp, "")
+	if len(squashed) < 3 {
+		return false
+	}
+	for _, part := range strings.Split(id, ".") {
+		if strings.HasPrefix(part, squashed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Trims the first matching suffix off name
+func trimAnySuffix(name string, suffixes []string) string {
+	for _, suffix := range suffixes {
+		if suffix != "" && strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
@@ -0,0 +1,107 @@
+package finder
+
+/*
+Linux.go holds the layout of Linux desktops, where apps are listed by their
+desktop entries and keep their data in the XDG base directories
+*/
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// LinuxPaths is the layout of freedesktop.org desktops
+type LinuxPaths struct {
+	SystemApplications      string // desktop entries installed by the distribution
+	LocalApplications       string // desktop entries installed by the administrator
+	UserApplications        string // desktop entries installed by the user
+	FlatpakApplications     string // desktop entries exported by system wide Flatpaks
+	UserFlatpakApplications string // desktop entries exported by per user Flatpaks
+	Config                  string // $XDG_CONFIG_HOME
+	Data                    string // $XDG_DATA_HOME
+	Cache                   string // $XDG_CACHE_HOME
+	State                   string // $XDG_STATE_HOME
+	Autostart               string // desktop entries started on login
+	FlatpakData             string // per app data of Flatpaks, named after the app ID
+}
+
+// Creates the Linux layout of the system at root for the home directory,
+// which is expected to be rebased onto root already.
+//
+// The XDG base directory variables are honoured when home is the running
+// user's, as they describe nobody else's
+func NewLinuxPaths(root, home string) LinuxPaths {
+	config := xdgDir("XDG_CONFIG_HOME", home, ".config")
+	data := xdgDir("XDG_DATA_HOME", home, ".local", "share")
+	return LinuxPaths{
+		SystemApplications:      filepath.Join(root, "/usr/share/applications"),
+		LocalApplications:       filepath.Join(root, "/usr/local/share/applications"),
+		UserApplications:        filepath.Join(data, "applications"),
+		FlatpakApplications:     filepath.Join(root, "/var/lib/flatpak/exports/share/applications"),
+		UserFlatpakApplications: filepath.Join(data, "flatpak", "exports", "share", "applications"),
+		Config:                  config,
+		Data:                    data,
+		Cache:                   xdgDir("XDG_CACHE_HOME", home, ".cache"),
+		State:                   xdgDir("XDG_STATE_HOME", home, ".local", "state"),
+		Autostart:               filepath.Join(config, "autostart"),
+		FlatpakData:             filepath.Join(home, ".var", "app"),
+	}
+}
+
+// Returns the directory set in the environment variable, which the
+// specification requires to be absolute, or its default below home
+func xdgDir(env, home string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) && home == os.Getenv("HOME") {
+		return dir
+	}
+	return filepath.Join(append([]string{home}, fallback...)...)
+}
+
+// ApplicationRoots returns every directory searched for desktop entries
+func (p LinuxPaths) ApplicationRoots() []string {
+	return []string{
+		p.SystemApplications,
+		p.LocalApplications,
+		p.UserApplications,
+		p.FlatpakApplications,
+		p.UserFlatpakApplications,
+	}
+}
+
+// Returns a string of all available paths to search
+func (p LinuxPaths) SearchPaths() []string {
+	return append(p.ApplicationRoots(),
+		p.Config,
+		p.Data,
+		p.Cache,
+		p.State,
+		p.Autostart,
+		p.FlatpakData,
+	)
+}
+
+// Category returns the display label of a search root
+func (p LinuxPaths) Category(root string) string {
+	switch root {
+	case p.SystemApplications, p.LocalApplications, p.UserApplications, p.FlatpakApplications, p.UserFlatpakApplications:
+		return "Applications"
+	case p.Config:
+		return "Config"
+	case p.Data:
+		return "Application Data"
+	case p.Cache:
+		return "Caches"
+	case p.State:
+		return "State"
+	case p.Autostart:
+		return "Autostart"
+	case p.FlatpakData:
+		return "Flatpak Data"
+	}
+	return "Other"
+}
+
+// Returns how many folder levels below the root are searched
+func (p LinuxPaths) SearchDepth(root string) int {
+	return STANDARD_DEPTH
+}
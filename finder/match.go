@@ -0,0 +1,121 @@
+package finder
+
+import (
+	"os"
+	"sync"
+
+	"github.com/alewtschuk/rmapp/index"
+	"github.com/alewtschuk/rmapp/options"
+)
+
+// Kind is the type of filesystem entry a match points at
+type Kind string
+
+// Kinds of matched entries
+const (
+	KindFile    Kind = "file"
+	KindDir     Kind = "dir"
+	KindSymlink Kind = "symlink"
+	KindBundle  Kind = "bundle"
+)
+
+// Rule is the matcher rule that caused a path to match
+type Rule string
+
+// Rules applied by the matcher, strongest first
+const (
+	RuleBundleInfo   Rule = "bundle info"         // bundle's Info.plist declares the bundle ID
+	RuleReceipt      Rule = "package receipt"     // listed in the bill of materials of the app's installer package
+	RuleLaunchJob    Rule = "launchd job"         // launchd job whose program lives in the app's bundle
+	RuleBundleID     Rule = "bundle id"           // full bundle ID found in the name
+	RuleIdentifier   Rule = "embedded identifier" // identifier of a helper, service, extension or group
+	RuleBundleIDBase Rule = "bundle id base"      // bundle ID without its numeric suffix found in the name
+	RuleToken        Rule = "app name tokens"     // app name tokens found in order in the name
+)
+
+// Match is a path flagged as belonging to the app along with how it was found
+type Match struct {
+	Path       string     // full path of the matched entry
+	Root       string     // search root the entry was found under
+	Kind       Kind       // type of the entry
+	Category   string     // label of the search root (e.g. "Caches")
+	Rule       Rule       // rule that caused the match
+	Identifier Identifier // identifier that matched
+	Confidence float64    // how likely the entry belongs to the app, from 0 to 1
+	Owner      string     // owner identifier declared by a container's metadata
+
+	index       *index.Index // scan index sizes are read from, nil reads from disk
+	diskOnce    sync.Once
+	diskSize    int64
+	logicalOnce sync.Once
+	logicalSize int64
+}
+
+// NewMatch creates a match for a path found outside of a scan,
+// reading the entry's kind from disk
+func NewMatch(path string) *Match {
+	kind := KindFile
+	if info, err := os.Lstat(path); err == nil {
+		kind = kindOf(info.Mode())
+	}
+	return &Match{Path: path, Kind: kind, Rule: RuleBundleID, Confidence: 1.0}
+}
+
+// PathSize returns the logical or on disk size of path, read
+// through the scan index unless it is disabled
+func PathSize(path string, opts options.Options) int64 {
+	match := NewMatch(path)
+	match.index = newFinder(opts).Index
+	return match.Size(opts.Logical)
+}
+
+// Size returns the logical or on disk size of the match.
+//
+// Sizes are computed once on first use and cached afterwards. Matches
+// found by a scan read their sizes through the scan index
+func (m *Match) Size(logical bool) int64 {
+	if logical {
+		m.logicalOnce.Do(func() { m.logicalSize = m.readSize(true) })
+		return m.logicalSize
+	}
+	m.diskOnce.Do(func() { m.diskSize = m.readSize(false) })
+	return m.diskSize
+}
+
+// Reads the size from the scan index when the match has one, otherwise from disk
+func (m *Match) readSize(logical bool) int64 {
+	switch {
+	case m.index != nil:
+		return m.index.Size(m.Path, logical)
+	case logical:
+		return getLogicalSize(m.Path)
+	default:
+		return GetDiskSize(m.Path)
+	}
+}
+
+// IsSymlink reports if the match is a symbolic link
+func (m *Match) IsSymlink() bool {
+	return m.Kind == KindSymlink
+}
+
+// Paths returns the paths of the matches
+func Paths(matches []*Match) []string {
+	paths := make([]string, 0, len(matches))
+	for _, match := range matches {
+		paths = append(paths, match.Path)
+	}
+	return paths
+}
+
+// Maps a file mode to the kind of match
+func kindOf(mode os.FileMode) Kind {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return KindSymlink
+	case mode.IsDir():
+		return KindDir
+	default:
+		return KindFile
+	}
+}
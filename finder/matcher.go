@@ -1,29 +1,79 @@
 package finder
 
 import (
+	"path/filepath"
 	"strings"
 	"unicode"
+
+	"github.com/alewtschuk/rmapp/launchd"
 )
 
 // Checks if the file/directory name contains the appName or bundleID
 func (f Finder) isMatch(filename string, ctx ScanContext) bool {
+	_, _, ok := f.matchIdentifier(filename, ctx)
+	return ok
+}
+
+// Checks the file/directory name against every identifier of the target
+// and returns the identifier and rule that caused the match
+func (f Finder) matchIdentifier(filename string, ctx ScanContext) (Identifier, Rule, bool) {
 	filename = strings.ToLower(filename)
 	bundleID := strings.ToLower(ctx.BundleID)
 
-	// Match full bundleID anywhere in the filename
-	if strings.Contains(filename, bundleID) {
-		return true
+	// Bundle ID may be unknown when only leftovers of an app remain
+	if bundleID != "" {
+		// Match full bundleID anywhere in the filename
+		if strings.Contains(filename, bundleID) {
+			return Identifier{Value: ctx.BundleID, Source: SourceBundleID}, RuleBundleID, true
+		}
+
+		// Handle numeric suffix variations in bundle ID
+		// For example: com.microsoft.teams2 should match com.microsoft.teams (detected edge case)
+		bundleIDBase := strings.TrimRightFunc(bundleID, unicode.IsDigit)
+		if bundleIDBase != bundleID && strings.Contains(filename, bundleIDBase) {
+			return Identifier{Value: ctx.BundleID, Source: SourceBundleID}, RuleBundleIDBase, true
+		}
 	}
 
-	// Handle numeric suffix variations in bundle ID
-	// For example: com.microsoft.teams2 should match com.microsoft.teams (detected edge case)
-	bundleIDBase := strings.TrimRightFunc(bundleID, unicode.IsDigit)
-	if bundleIDBase != bundleID && strings.Contains(filename, bundleIDBase) {
-		return true
+	// Check identifiers of embedded helpers, services and extensions.
+	// Executable names are generic, so the app name takes precedence over them
+	var executable *Identifier
+	for _, id := range ctx.Identifiers {
+		if !id.matches(filename) {
+			continue
+		}
+		if id.Source != SourceExecutable {
+			return id, RuleIdentifier, true
+		}
+		if executable == nil {
+			executable = &id
+		}
 	}
 
 	// Otherwise fallback to token check
-	return searchName(ctx, filename)
+	if searchName(ctx, filename) {
+		return Identifier{Value: ctx.AppName, Source: SourceName}, RuleToken, true
+	}
+	if executable != nil {
+		return *executable, RuleIdentifier, true
+	}
+	return Identifier{}, "", false
+}
+
+// Checks a regular file by name and, for launchd job plists with
+// unrelated names, by the program the job runs
+func (f Finder) matchFile(path string, ctx ScanContext) (Identifier, Rule, bool) {
+	if id, rule, ok := f.matchIdentifier(filepath.Base(path), ctx); ok {
+		return id, rule, true
+	}
+	if !launchd.IsJobPath(path) {
+		return Identifier{}, "", false
+	}
+	job, err := launchd.ReadJob(path)
+	if err != nil || !job.RunsFrom(ctx.BundlePath, ctx.BundleID) {
+		return Identifier{}, "", false
+	}
+	return Identifier{Value: job.Label, Source: SourceLaunchJob}, RuleLaunchJob, true
 }
 
 // Extract domain hint from bundleID (e.g. "com.theapp.App" to "theapp")
@@ -48,6 +98,9 @@ func tokenize(name string) []string {
 // Utilizes KNP search algorithm to find match occurences
 // of the app name inside the file name.
 func searchName(ctx ScanContext, filename string) bool {
+	if len(ctx.TokenizedApp) == 0 {
+		return false
+	}
 
 	//Tokenize files and build lps
 	filename = strings.TrimRightFunc(filename, unicode.IsDigit) //trim any numeric suffix off filename
@@ -93,6 +146,9 @@ func buildLPS(pattern []string) []int {
 
 	//Create lps array to size of pattern array and set first lps index
 	lps := make([]int, len(pattern))
+	if len(pattern) == 0 {
+		return lps
+	}
 	lps[0] = 0 //will always be zero as no prefix-sufix can exist yet
 
 	//Current index to build in lps
@@ -0,0 +1,135 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/options"
+)
+
+// Suffixes trimmed off bundle ID named entries to recover the identifier
+var orphanSuffixes = []string{".plist", ".savedState", ".binarycookies", ".log"}
+
+// Matches the developer team ID prefixing shared group identifiers (e.g. ABCDE12345.com.vendor.app)
+var teamPrefixPattern = regexp.MustCompile(`^[A-Z0-9]{10}\.`)
+
+// Matches the hardware UUID suffix of per host preferences in Preferences/ByHost
+var byHostPattern = regexp.MustCompile(`\.[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}$`)
+
+// Orphan is bundle ID named data left behind by an app that is no longer installed
+type Orphan struct {
+	BundleID string   // identifier inferred from the entries' names
+	Matches  []*Match // entries named after the identifier
+}
+
+// Returns the combined size of the orphan's entries
+func (o Orphan) Size(logical bool) int64 {
+	var size int64
+	for _, match := range o.Matches {
+		size += match.Size(logical)
+	}
+	return size
+}
+
+// FindOrphans lists the bundle ID named entries directly inside every search
+// root that do not belong to any of the installed identifiers, grouped by the
+// identifier inferred from their names, largest first.
+//
+// An entry belongs to an installed identifier if either is the other or a
+// dot separated prefix of it, so com.vendor.app.helper is owned by com.vendor.app.
+// Application roots and package receipts are not scanned as their entries
+// are not named after app bundle IDs
+func FindOrphans(installed []string, opts options.Options) []Orphan {
+	f := newFinder(opts)
+
+	index := map[string]bool{}
+	for _, id := range installed {
+		if id != "" {
+			index[normalizeIdentifier(id)] = true
+		}
+	}
+
+	groups := map[string][]*Match{}
+	for _, root := range f.AllSearchPaths() {
+		if f.isApplicationRoot(root) || root == f.Darwin.System.SystemReceipts {
+			continue
+		}
+		dirs := []string{root}
+		if root == f.Darwin.UserPaths.PreferencesPath {
+			dirs = append(dirs, filepath.Join(root, "ByHost"))
+		}
+
+		for _, dir := range dirs {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				path := filepath.Join(dir, entry.Name())
+				id := orphanIdentifier(path, entry, f.isContainerRoot(root))
+				if !looksLikeBundleID(id) || isInstalled(id, index) {
+					continue
+				}
+
+				match := NewMatch(path)
+				match.Root = root
+				match.Category = f.Category(root)
+				match.index = f.Index
+				groups[id] = append(groups[id], match)
+			}
+		}
+	}
+
+	var orphans []Orphan
+	for id, matches := range groups {
+		sort.Slice(matches, func(i, j int) bool { return matches[i].Path < matches[j].Path })
+		orphans = append(orphans, Orphan{BundleID: id, Matches: matches})
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		si, sj := orphans[i].Size(opts.Logical), orphans[j].Size(opts.Logical)
+		if si != sj {
+			return si > sj
+		}
+		return orphans[i].BundleID < orphans[j].BundleID
+	})
+	return orphans
+}
+
+// Recovers the bundle ID an entry is named after, preferring the identifier
+// declared in container metadata
+func orphanIdentifier(path string, entry os.DirEntry, container bool) string {
+	if container && entry.IsDir() {
+		if declared := ContainerIdentifier(path); declared != "" {
+			return declared
+		}
+	}
+	id := trimAnySuffix(entry.Name(), orphanSuffixes)
+	id = byHostPattern.ReplaceAllString(id, "")
+	return strings.TrimPrefix(teamPrefixPattern.ReplaceAllString(id, ""), "group.")
+}
+
+// Checks the identifier against the installed index, walking up its
+// dot separated prefixes and down to installed identifiers it prefixes
+func isInstalled(id string, index map[string]bool) bool {
+	id = normalizeIdentifier(id)
+	for prefix := id; strings.Contains(prefix, "."); prefix = prefix[:strings.LastIndex(prefix, ".")] {
+		if index[prefix] {
+			return true
+		}
+	}
+	for installed := range index {
+		if strings.HasPrefix(installed, id+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Lowercases an identifier and strips the team ID and group prefixes
+func normalizeIdentifier(id string) string {
+	id = teamPrefixPattern.ReplaceAllString(id, "")
+	return strings.ToLower(strings.TrimPrefix(id, "group."))
+}
@@ -0,0 +1,86 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/options"
+)
+
+// Owner is an installed app that likely owns a path
+type Owner struct {
+	Target Target // app the path was attributed to
+	Match  *Match // path component the app's identifiers matched
+}
+
+// FindOwners attributes a path to the targets whose identifiers or name
+// match one of its components, using the same rules a scan uses.
+//
+// Components are checked from the search root holding the path down to the
+// path itself, or every component for paths outside all search roots. Owners
+// are ordered by confidence with the most likely first
+func FindOwners(path string, targets []Target, opts options.Options) []Owner {
+	f := newFinder(opts)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	root := rootOf(path, f.AllSearchPaths())
+	if root == "" {
+		root = string(os.PathSeparator)
+	}
+	segments := strings.Split(relPath(root, path), string(os.PathSeparator))
+
+	var owners []Owner
+	for _, target := range targets {
+		if match := f.ownerMatch(target, root, segments); match != nil && match.Confidence >= opts.MinConfidence {
+			owners = append(owners, Owner{Target: target, Match: match})
+		}
+	}
+
+	sort.SliceStable(owners, func(i, j int) bool {
+		if owners[i].Match.Confidence != owners[j].Match.Confidence {
+			return owners[i].Match.Confidence > owners[j].Match.Confidence
+		}
+		return strings.ToLower(owners[i].Target.AppName) < strings.ToLower(owners[j].Target.AppName)
+	})
+	return owners
+}
+
+// Returns the highest confidence match of the target on the path's components,
+// nil if no component matched
+func (f Finder) ownerMatch(target Target, root string, segments []string) *Match {
+	ctx := f.newScanContext(target, root, nil)
+	path := filepath.Join(append([]string{root}, segments...)...)
+
+	// Anything inside the bundle itself belongs to it
+	if target.BundlePath != "" && (path == target.BundlePath || strings.HasPrefix(path, target.BundlePath+string(os.PathSeparator))) {
+		id := Identifier{Value: target.BundleID, Source: SourceBundleID}
+		return ctx.newMatch(target.BundlePath, KindBundle, id, RuleBundleInfo)
+	}
+
+	var best *Match
+	current := root
+	for _, name := range segments {
+		current = filepath.Join(current, name)
+		kind := KindDir
+		if info, err := os.Lstat(current); err == nil {
+			kind = kindOf(info.Mode())
+		}
+
+		// Files may also match by their contents, such as a launchd job running the app
+		id, rule, ok := f.matchIdentifier(name, ctx)
+		if kind == KindFile {
+			id, rule, ok = f.matchFile(current, ctx)
+		}
+		if !ok {
+			continue
+		}
+		if match := ctx.newMatch(current, kind, id, rule); best == nil || match.Confidence > best.Confidence {
+			best = match
+		}
+	}
+	return best
+}
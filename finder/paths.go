@@ -0,0 +1,248 @@
+package finder
+
+/*
+Paths.go holds the per platform layout of the folders apps are installed
+to and store their data in, so the same scan runs over either
+*/
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"github.com/alewtschuk/rmapp/options"
+)
+
+// Platforms the finder knows the layout of
+const (
+	DARWIN string = "darwin" // macOS .app bundles and Library folders
+	LINUX  string = "linux"  // desktop entries and XDG base directories
+)
+
+// PathProvider lays out where a platform keeps apps and their data
+type PathProvider interface {
+	// ApplicationRoots returns every directory searched for app bundles
+	ApplicationRoots() []string
+	// SearchPaths returns every directory searched, the application roots included
+	SearchPaths() []string
+	// Category returns the display label of a search root
+	Category(root string) string
+	// SearchDepth returns how many folder levels below the root are searched
+	SearchDepth(root string) int
+}
+
+// Returns the platform whose layout is searched, defaulting to the one rmapp runs on
+func Platform(opts options.Options) string {
+	if opts.Platform != "" {
+		return opts.Platform
+	}
+	if runtime.GOOS == DARWIN {
+		return DARWIN
+	}
+	return LINUX
+}
+
+// Checks the platform name passed on the command line
+func ValidPlatform(name string) error {
+	if name == "" || name == DARWIN || name == LINUX {
+		return nil
+	}
+	return fmt.Errorf("unknown platform %q, use %s or %s", name, DARWIN, LINUX)
+}
+
+// Rebase returns where path of the searched system is found, which is
+// below the root for offline analysis of a disk image or backup
+func Rebase(path string, opts options.Options) string {
+	if opts.Root == "" {
+		return path
+	}
+	return filepath.Join(opts.Root, path)
+}
+
+// HomeDir returns the home directory searched, rebased onto the root
+func HomeDir(opts options.Options) string {
+	home := opts.Home
+	if home == "" {
+		home = os.Getenv("HOME")
+	}
+	return Rebase(home, opts)
+}
+
+// DarwinPaths is the macOS layout
+type DarwinPaths struct {
+	OSMain    OSMainPaths
+	System    SystemPaths
+	UserPaths UserPaths
+}
+
+// Creates the macOS layout of the system at root for the home directory,
+// which is expected to be rebased onto root already
+func NewDarwinPaths(root, home string) DarwinPaths {
+	at := func(path string) string {
+		return filepath.Join(root, path)
+	}
+	return DarwinPaths{
+		OSMain: OSMainPaths{
+			RootApplicationsPath: at("/Applications"),
+			UserApplicationsPath: fmt.Sprintf("%s/Applications", home),
+			VolumesPath:          at("/Volumes"),
+		},
+		System: SystemPaths{
+			SystemSupportFilesPath:      at("/Library/Application Support"),
+			SystemCrashReports:          at("/Library/Application Support/CrashReporter"),
+			SystemCaches:                at("/Library/Caches"),
+			SystemExtensions:            at("/Library/Extensions"),
+			SystemInternetPlugIns:       at("/Library/Internet Plug-Ins"),
+			SystemLaunchAgents:          at("/Library/LaunchAgents"),
+			SystemLaunchDaemons:         at("/Library/LaunchDaemons"),
+			SystemLogs:                  at("/Library/Logs"),
+			SystemPrivilegedHelperTools: at("/Library/PrivilegedHelperTools"),
+			SystemReceipts:              at("/var/db/receipts"),
+			SystemBin:                   at("/usr/local/bin"),
+			SystemOpt:                   at("/usr/local/opt"),
+			SystemSbin:                  at("/usr/local/sbin"),
+			SystemShare:                 at("/usr/local/share"),
+			SystemVar:                   at("/usr/local/var"),
+		},
+		UserPaths: UserPaths{
+			AppSupportFilesPath: fmt.Sprintf("%s/Library/Application Support", home),
+			PreferencesPath:     fmt.Sprintf("%s/Library/Preferences", home),
+			CachesPath:          fmt.Sprintf("%s/Library/Caches", home),
+			ContainersPath:      fmt.Sprintf("%s/Library/Containers", home),
+			SavedStatePath:      fmt.Sprintf("%s/Library/Saved Application State", home),
+			HTTPStorages:        fmt.Sprintf("%s/Library/HTTPStorages", home),
+			GroupContainers:     fmt.Sprintf("%s/Library/Group Containers", home),
+			InternetPlugIns:     fmt.Sprintf("%s/Library/Internet Plug-Ins", home),
+			LaunchAgents:        fmt.Sprintf("%s/Library/LaunchAgents", home),
+			Logs:                fmt.Sprintf("%s/Library/Logs", home),
+			WebKit:              fmt.Sprintf("%s/Library/WebKit", home),
+			ApplicationScripts:  fmt.Sprintf("%s/Library/Application Scripts", home),
+		},
+	}
+}
+
+// ApplicationRoots returns every directory searched for .app bundles.
+//
+// Includes /Applications, ~/Applications and the Applications
+// folder of each mounted external volume
+func (p DarwinPaths) ApplicationRoots() []string {
+	roots := []string{p.OSMain.RootApplicationsPath, p.OSMain.UserApplicationsPath}
+	return append(roots, p.volumeApplicationPaths()...)
+}
+
+// Returns the Applications folders of mounted volumes, skipping the
+// boot volume which is only a symlink back to /
+func (p DarwinPaths) volumeApplicationPaths() []string {
+	volumes, err := os.ReadDir(p.OSMain.VolumesPath)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, volume := range volumes {
+		if volume.Type()&os.ModeSymlink != 0 || !volume.IsDir() {
+			continue
+		}
+		path := filepath.Join(p.OSMain.VolumesPath, volume.Name(), "Applications")
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Returns a string of all available paths to search
+func (p DarwinPaths) SearchPaths() []string {
+	paths := []string{
+		p.OSMain.RootApplicationsPath,
+		p.OSMain.UserApplicationsPath,
+		p.System.SystemSupportFilesPath,
+		p.System.SystemCrashReports,
+		p.System.SystemCaches,
+		p.System.SystemExtensions,
+		p.System.SystemInternetPlugIns,
+		p.System.SystemLaunchAgents,
+		p.System.SystemLaunchDaemons,
+		p.System.SystemLogs,
+		p.System.SystemPrivilegedHelperTools,
+		p.System.SystemReceipts,
+		p.System.SystemBin,
+		p.System.SystemOpt,
+		p.System.SystemSbin,
+		p.System.SystemShare,
+		p.System.SystemVar,
+		p.UserPaths.AppSupportFilesPath,
+		p.UserPaths.PreferencesPath,
+		p.UserPaths.CachesPath,
+		p.UserPaths.ContainersPath,
+		p.UserPaths.SavedStatePath,
+		p.UserPaths.HTTPStorages,
+		p.UserPaths.GroupContainers,
+		p.UserPaths.InternetPlugIns,
+		p.UserPaths.LaunchAgents,
+		p.UserPaths.Logs,
+		p.UserPaths.WebKit,
+		p.UserPaths.ApplicationScripts,
+	}
+	return append(paths, p.volumeApplicationPaths()...)
+}
+
+// Category returns the display label of a search root
+func (p DarwinPaths) Category(root string) string {
+	switch root {
+	case p.OSMain.RootApplicationsPath, p.OSMain.UserApplicationsPath:
+		return "Applications"
+	case p.System.SystemSupportFilesPath, p.UserPaths.AppSupportFilesPath:
+		return "Application Support"
+	case p.System.SystemCrashReports:
+		return "Crash Reports"
+	case p.System.SystemCaches, p.UserPaths.CachesPath:
+		return "Caches"
+	case p.System.SystemExtensions:
+		return "Extensions"
+	case p.System.SystemInternetPlugIns, p.UserPaths.InternetPlugIns:
+		return "Internet Plug-Ins"
+	case p.System.SystemLaunchAgents, p.UserPaths.LaunchAgents:
+		return "Launch Agents"
+	case p.System.SystemLaunchDaemons:
+		return "Launch Daemons"
+	case p.System.SystemLogs, p.UserPaths.Logs:
+		return "Logs"
+	case p.System.SystemPrivilegedHelperTools:
+		return "Privileged Helper Tools"
+	case p.System.SystemReceipts:
+		return "Receipts"
+	case p.System.SystemBin, p.System.SystemSbin:
+		return "Binaries"
+	case p.System.SystemOpt, p.System.SystemShare, p.System.SystemVar:
+		return "Local Data"
+	case p.UserPaths.PreferencesPath:
+		return "Preferences"
+	case p.UserPaths.ContainersPath:
+		return "Containers"
+	case p.UserPaths.SavedStatePath:
+		return "Saved State"
+	case p.UserPaths.HTTPStorages:
+		return "HTTP Storages"
+	case p.UserPaths.GroupContainers:
+		return "Group Containers"
+	case p.UserPaths.WebKit:
+		return "WebKit"
+	case p.UserPaths.ApplicationScripts:
+		return "Application Scripts"
+	}
+	if slices.Contains(p.volumeApplicationPaths(), root) {
+		return "Applications"
+	}
+	return "Other"
+}
+
+// Returns how many folder levels below the root are searched
+func (p DarwinPaths) SearchDepth(root string) int {
+	if root == p.UserPaths.PreferencesPath {
+		return PREFERENCES_DEPTH
+	}
+	return STANDARD_DEPTH
+}
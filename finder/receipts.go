@@ -0,0 +1,232 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/bom"
+	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/plist"
+)
+
+// Category of matches listed in an installer package's bill of materials
+const CategoryReceipt = "from package receipt"
+
+// Receipt is the record the installer keeps of an installed .pkg
+type Receipt struct {
+	PackageID     string // package identifier (e.g. com.vendor.pkg.App)
+	Version       string // package version
+	InstallPrefix string // absolute directory the BOM paths are relative to
+	Path          string // path to the receipt plist
+}
+
+// Returns the path of the bill of materials stored next to the receipt
+func (r Receipt) BOMPath() string {
+	return strings.TrimSuffix(r.Path, ".plist") + ".bom"
+}
+
+// Returns the absolute paths of the files, symlinks and bundle directories
+// the package installed.
+//
+// Plain directories are left out as they are usually shared with other
+// packages (/usr/local/bin), and entries inside a listed bundle are
+// covered by the bundle itself
+func (r Receipt) Files() ([]string, error) {
+	entries, err := bom.ReadFile(r.BOMPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	var bundle string
+	for _, entry := range entries {
+		// Entries escaping the install prefix were not installed by the package
+		relPath := filepath.FromSlash(entry.Path)
+		if entry.Path == "." || !filepath.IsLocal(relPath) {
+			continue
+		}
+		path := filepath.Join(r.InstallPrefix, relPath)
+		if bundle != "" && strings.HasPrefix(path, bundle+string(os.PathSeparator)) {
+			continue
+		}
+
+		switch entry.Type {
+		case bom.TypeFile, bom.TypeLink:
+			files = append(files, path)
+		case bom.TypeDir:
+			if isPackage(filepath.Base(path)) {
+				files = append(files, path)
+				bundle = path
+			}
+		}
+	}
+	return files, nil
+}
+
+// ReadReceipts reads every third party receipt in dir, skipping
+// the receipts of macOS system packages
+func ReadReceipts(dir string) []Receipt {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var receipts []Receipt
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".plist") || strings.HasPrefix(name, "com.apple.") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		dict, err := plist.ReadDict(path)
+		if err != nil {
+			continue
+		}
+
+		receipt := Receipt{
+			PackageID:     plist.String(dict, "PackageIdentifier"),
+			Version:       plist.String(dict, "PackageVersion"),
+			InstallPrefix: filepath.Join(string(os.PathSeparator), plist.String(dict, "InstallPrefixPath")),
+			Path:          path,
+		}
+		if receipt.PackageID == "" {
+			receipt.PackageID = strings.TrimSuffix(name, ".plist")
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts
+}
+
+// Reads the third party receipts of the searched system, with their
+// install prefixes rebased onto it. Only macOS keeps receipts
+func (f Finder) systemReceipts(opts options.Options) []Receipt {
+	if Platform(opts) != DARWIN {
+		return nil
+	}
+	receipts := ReadReceipts(f.Darwin.System.SystemReceipts)
+	for i := range receipts {
+		receipts[i].InstallPrefix = Rebase(receipts[i].InstallPrefix, opts) // prefixes name the system the receipts came from
+	}
+	return receipts
+}
+
+// MatchReceipts finds the packages that installed the target and returns
+// every path their bills of materials list that still exists.
+//
+// A package belongs to the target if its BOM lists the target's bundle,
+// or if its identifier matches the target under the usual rules, in which
+// case the listed paths inherit that rule's confidence. The receipt itself
+// is matched too, which is what pkgutil --forget removes.
+//
+// Suites install several apps from one package. The other installed
+// bundles are left out and the rest of the package scores low, as those
+// apps may still use it
+func MatchReceipts(target Target, receipts []Receipt, installed []Bundle, opts options.Options) []*Match {
+	if len(receipts) == 0 {
+		return nil
+	}
+	f := newFinder(opts)
+	ctx := f.newScanContext(target, f.Darwin.System.SystemReceipts, nil)
+
+	var others []string
+	for _, bundle := range installed {
+		if bundle.Path != target.BundlePath {
+			others = append(others, bundle.Path)
+		}
+	}
+
+	var matches []*Match
+	for _, receipt := range receipts {
+		files, err := receipt.Files()
+		if err != nil {
+			continue
+		}
+		if confidence, ok := f.receiptConfidence(receipt, files, ctx); ok {
+			matches = append(matches, f.receiptMatches(receipt, files, confidence, target.BundlePath, others)...)
+		}
+	}
+	return matches
+}
+
+// Scores how likely the package installed the target of ctx, returning
+// false if it did not
+func (f Finder) receiptConfidence(receipt Receipt, files []string, ctx ScanContext) (float64, bool) {
+	if ctx.BundlePath != "" && containsPath(files, ctx.BundlePath) {
+		return 1.0, true
+	}
+	if id, rule, ok := f.matchIdentifier(receipt.PackageID, ctx); ok {
+		return scoreMatch(receipt.PackageID, rule, id, ctx), true
+	}
+	return 0, false
+}
+
+// Returns the receipt and the package's files that still exist as matches
+// of the app at bundlePath, leaving out the files inside the other apps
+func (f Finder) receiptMatches(receipt Receipt, files []string, confidence float64, bundlePath string, others []string) []*Match {
+	var own []string
+	for _, path := range files {
+		if !coveredBy(path, others) {
+			own = append(own, path)
+		}
+	}
+	if len(own) < len(files) {
+		confidence = min(confidence, 0.3)
+	}
+
+	id := Identifier{Value: receipt.PackageID, Source: SourceReceipt}
+	var matches []*Match
+	for _, path := range append(own, receipt.Path, receipt.BOMPath()) {
+		if path == bundlePath {
+			continue
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		matches = append(matches, &Match{
+			Path:       path,
+			Root:       f.Darwin.System.SystemReceipts,
+			Kind:       kindOf(info.Mode()),
+			Category:   CategoryReceipt,
+			Rule:       RuleReceipt,
+			Identifier: id,
+			Confidence: confidence,
+			index:      f.Index,
+		})
+	}
+	return matches
+}
+
+// Adds the matches not already covered by another match, as the
+// package's files may also have been found by name
+func mergeMatches(matches, extra []*Match) []*Match {
+	paths := Paths(matches)
+	for _, match := range extra {
+		if !coveredBy(match.Path, paths) {
+			matches = append(matches, match)
+			paths = append(paths, match.Path)
+		}
+	}
+	return matches
+}
+
+// Checks if path is or lives inside one of the paths
+func coveredBy(path string, paths []string) bool {
+	for _, covering := range paths {
+		if path == covering || strings.HasPrefix(path, covering+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the paths contain path exactly
+func containsPath(paths []string, path string) bool {
+	for _, candidate := range paths {
+		if candidate == path {
+			return true
+		}
+	}
+	return false
+}
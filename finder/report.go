@@ -1,9 +1,8 @@
 package finder
 
 import (
-	"errors"
 	"fmt"
-	"os"
+	"slices"
 	"sort"
 	"strings"
 
@@ -13,14 +12,16 @@ import (
 
 // Holds the match metadata
 type MatchMeta struct {
-	Path      string
-	SizeStr   string
-	PrintLine string
-	Size      int64
+	Path          string
+	SizeStr       string
+	ConfidenceStr string
+	PrintLine     string
+	Category      string
+	Size          int64
 }
 
 // Generates the report for when program is called with --peek
-func GenerateReport(matches []string, appName string, opts options.Options) {
+func GenerateReport(matches []*Match, appName string, opts options.Options) {
 
 	var (
 		size              int64
@@ -28,9 +29,9 @@ func GenerateReport(matches []string, appName string, opts options.Options) {
 		numFiles          int
 		maxLineWidth      int
 		metas             []MatchMeta
-		symlink           bool
 		printLine         string
 		printLineStripped string
+		lowConfidence     int
 	)
 
 	switch {
@@ -41,51 +42,42 @@ func GenerateReport(matches []string, appName string, opts options.Options) {
 		}
 
 		for _, match := range matches {
-			if opts.Logical {
-				size = getLogicalSize(match)
-			} else {
-				size = GetDiskSize(match)
-			}
+			size = match.Size(opts.Logical)
 			totalSize += size
 			numFiles++
 
-			matchInfo, err := os.Lstat(match)
-			if err != nil {
-				var pathError *os.PathError
-				if errors.As(err, &pathError) {
-					fmt.Printf("%s %s", pfmt.ApplyColor("[rmapp] path error at match:", 3), match)
-					continue
-				}
-			}
-
-			if matchInfo.Mode()&os.ModeSymlink != 0 {
-				symlink = true
-			} else {
-				symlink = false
-			}
-
 			sizeStr := FormatSize(size)
 			appColored := pfmt.ApplyColor(appName, 2)
-			pathColored := pfmt.ApplyColor(match, 3)
+			pathColored := pfmt.ApplyColor(match.Path, 3)
+			owner := ""
+			if match.Owner != "" {
+				owner = fmt.Sprintf(" (owner %s)", match.Owner)
+			}
 
-			if !symlink {
-				printLine = fmt.Sprintf("• Match %s FOUND at: %s", appColored, pathColored)
-				printLineStripped = fmt.Sprintf("• Match %s FOUND at: %s", appName, match)
+			if !match.IsSymlink() {
+				printLine = fmt.Sprintf("• Match %s FOUND at: %s%s", appColored, pathColored, owner)
+				printLineStripped = fmt.Sprintf("• Match %s FOUND at: %s%s", appName, match.Path, owner)
 			} else {
 				printLine = fmt.Sprintf("• Symlink match %s FOUND at: %s", appColored, pathColored)
-				printLineStripped = fmt.Sprintf("• Symlink match %s FOUND at: %s", appName, match)
+				printLineStripped = fmt.Sprintf("• Symlink match %s FOUND at: %s", appName, match.Path)
 			}
 
 			if len(printLineStripped) > maxLineWidth {
 				maxLineWidth = len(printLineStripped)
 			}
 
+			if match.Confidence < LOW_CONFIDENCE {
+				lowConfidence++
+			}
+
 			metas = append(metas,
 				MatchMeta{
-					Path:      match,
-					SizeStr:   sizeStr,
-					PrintLine: printLine,
-					Size:      size,
+					Path:          match.Path,
+					SizeStr:       sizeStr,
+					ConfidenceStr: FormatConfidence(match.Confidence),
+					PrintLine:     printLine,
+					Category:      match.Category,
+					Size:          size,
 				})
 		}
 
@@ -96,14 +88,28 @@ func GenerateReport(matches []string, appName string, opts options.Options) {
 
 		fmt.Printf("\nFound %s files for %s\n", pfmt.ApplyColor(fmt.Sprintf("%d", numFiles), 3), appName)
 
-		// Print all formatted
-		for _, meta := range metas {
-			lineStripped := StripColor(meta.PrintLine)
-			padding := maxLineWidth - len(lineStripped)
-			fmt.Printf("%s%s %s\n", meta.PrintLine, strings.Repeat(" ", padding), meta.SizeStr)
+		// Print all formatted, with files listed by package receipts in their own section
+		printMetas := func(receipts bool) {
+			for _, meta := range metas {
+				if (meta.Category == CategoryReceipt) != receipts {
+					continue
+				}
+				lineStripped := StripColor(meta.PrintLine)
+				padding := maxLineWidth - len(lineStripped)
+				fmt.Printf("%s%s %s %s\n", meta.PrintLine, strings.Repeat(" ", padding), meta.ConfidenceStr, meta.SizeStr)
+			}
+		}
+		printMetas(false)
+		if slices.ContainsFunc(metas, func(meta MatchMeta) bool { return meta.Category == CategoryReceipt }) {
+			fmt.Printf("\n%s\n", pfmt.ApplyColor("From package receipt:", 3))
+			printMetas(true)
 		}
 
-		fmt.Printf("→ Total: %s would be freed\n\n", FormatSize(totalSize))
+		fmt.Printf("→ Total: %s would be freed\n", FormatSize(totalSize))
+		if lowConfidence > 0 {
+			fmt.Printf("→ %s low confidence matches will only be removed after confirmation\n", pfmt.ApplyColor(fmt.Sprintf("%d", lowConfidence), 9))
+		}
+		fmt.Println()
 		fmt.Println("Run again without -p '--peek' to Trash files or with -f '--force' to delete files")
 
 	case opts.Size:
@@ -113,12 +119,7 @@ func GenerateReport(matches []string, appName string, opts options.Options) {
 		}
 
 		for _, match := range matches {
-			if opts.Logical {
-				size = getLogicalSize(match)
-			} else {
-				size = GetDiskSize(match)
-			}
-			totalSize += size
+			totalSize += match.Size(opts.Logical)
 		}
 
 		fmt.Printf("%s total size: %s\n\n", appName, FormatSize(totalSize))
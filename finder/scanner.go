@@ -9,8 +9,10 @@ import (
 	"strings"
 
 	"github.com/alewtschuk/pfmt"
-	"github.com/alewtschuk/rmapp/darwin"
+	"github.com/alewtschuk/rmapp/index"
 	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/platform"
+	"github.com/alewtschuk/rmapp/prompt"
 )
 
 // Handles the files/directories if there is a match
@@ -18,32 +20,31 @@ import (
 // Sends all matches to a channel for shared goroutine communication
 func (f *Finder) handleScan(d fs.DirEntry, subPath, rootPath string, ctx ScanContext, opts options.Options) error {
 	name := d.Name()
-	symlink := false
 
 	// If type is a file
-	if d.Type().IsRegular() && f.isMatch(name, ctx) {
-		f.emitMatch(name, subPath, ctx.MatchesChan, opts, symlink)
-		// if !f.Reported {
-		// 	fmt.Println()
-		// }
-
+	if d.Type().IsRegular() {
+		if id, rule, ok := f.matchFile(subPath, ctx); ok {
+			f.emitMatch(name, ctx.newMatch(subPath, KindFile, id, rule), ctx.MatchesChan, opts)
+		}
 		return nil
 	}
 
 	// If type is a symlink check symlink bit and if symlink contains match hueristics emit match
 	// Used to prevent dangling symlinks
-	if d.Type()&os.ModeSymlink != 0 && f.isMatch(name, ctx) {
-		symlink = true
-		f.emitMatch(name, subPath, ctx.MatchesChan, opts, symlink)
-		// if !f.Reported {
-		// 	fmt.Println()
-		// }
-
+	if d.Type()&os.ModeSymlink != 0 {
+		if id, rule, ok := f.matchIdentifier(name, ctx); ok {
+			f.emitMatch(name, ctx.newMatch(subPath, KindSymlink, id, rule), ctx.MatchesChan, opts)
+		}
 		return nil
 	}
 
 	// If type is a directory
 	if d.Type().IsDir() {
+		// Roots nested in this one are scanned on their own
+		if f.isSearchRoot(subPath) {
+			return fs.SkipDir
+		}
+
 		relPath, err := filepath.Rel(rootPath, subPath)
 		if err != nil {
 			return nil
@@ -51,11 +52,16 @@ func (f *Finder) handleScan(d fs.DirEntry, subPath, rootPath string, ctx ScanCon
 		pathSeg := strings.Split(relPath, string(os.PathSeparator))
 		depth := len(pathSeg)
 
-		if f.isMatch(name, ctx) {
-			f.emitMatch(name, subPath, ctx.MatchesChan, opts, symlink)
-			// if !f.Reported {
-			// 	fmt.Println()
-			// }
+		// Containers declare their owner in metadata which beats the folder name
+		if owner := f.containerOwner(subPath, rootPath, depth); owner != "" {
+			if id, rule, ok := f.matchIdentifier(owner, ctx); ok {
+				f.emitMatch(name, ctx.newContainerMatch(subPath, owner, id, rule), ctx.MatchesChan, opts)
+			}
+			return fs.SkipDir
+		}
+
+		if id, rule, ok := f.matchIdentifier(name, ctx); ok {
+			f.emitMatch(name, ctx.newMatch(subPath, KindDir, id, rule), ctx.MatchesChan, opts)
 			return fs.SkipDir
 		}
 
@@ -66,30 +72,31 @@ func (f *Finder) handleScan(d fs.DirEntry, subPath, rootPath string, ctx ScanCon
 	return nil
 }
 
-// Checks if the rootPath /Applications or ~/Applications contains the .app bundle.
+// Checks if the application root holds the .app bundle.
 //
-// Uses directory scanning and handling as .app bundles are a
-// specially defined directory type in MacOS, even though they contain
-// a filetype identier
-func (f *Finder) FindApp(rootPath string, ctx ScanContext) {
-	applications, err := os.ReadDir(rootPath) // get all directories in the rootPath
-	if err == nil {
-		// Check each .app bundle, extract the name, check for match and send to channel
-		for _, app := range applications {
-			if !app.IsDir() {
-				continue
-			}
-			name := app.Name()
-			if f.isMatch(name, ctx) {
-				ctx.MatchesChan <- filepath.Join(rootPath, name) // send full path for the channel
-			}
+// Discovers bundles in nested folders up to the finder's AppDepth as .app
+// bundles are a specially defined directory type in MacOS, even though they
+// contain a filetype identier. Every copy sharing the bundle ID is sent.
+//
+// Returns every bundle discovered below the root
+func (f *Finder) FindApp(rootPath string, ctx ScanContext) []Bundle {
+	bundles := DiscoverBundles([]string{rootPath}, f.AppDepth)
+	for _, bundle := range bundles {
+		if ctx.BundleID != "" && strings.EqualFold(bundle.Info.Identifier, ctx.BundleID) {
+			id := Identifier{Value: ctx.BundleID, Source: SourceBundleID}
+			ctx.MatchesChan <- ctx.newMatch(bundle.Path, KindBundle, id, RuleBundleInfo)
+			continue
+		}
+		if id, rule, ok := f.matchIdentifier(filepath.Base(bundle.Path), ctx); ok {
+			ctx.MatchesChan <- ctx.newMatch(bundle.Path, KindBundle, id, rule) // send full path for the channel
 		}
 	}
+	return bundles
 }
 
 // Walks the directory, ensures theres no error, passes to handle scan for further subpath walking
 func (f *Finder) FindAppFiles(rootPath string, ctx ScanContext, opts options.Options) {
-	err := filepath.WalkDir(rootPath,
+	err := index.WalkDir(f.dirs(), rootPath,
 		func(subPath string, d fs.DirEntry, err error) error {
 
 			//Safeguard check to ensure the root directory is not matched
@@ -109,7 +116,7 @@ func (f *Finder) FindAppFiles(rootPath string, ctx ScanContext, opts options.Opt
 		})
 
 	if err != nil {
-		fmt.Println("[rmapp] Error on path:", rootPath, err)
+		fmt.Fprintln(prompt.Output, "[rmapp] Error on path:", rootPath, err)
 	}
 }
 
@@ -128,32 +135,57 @@ func getLogicalSize(path string) int64 {
 
 // Gets the file size in bytes
 func GetDiskSize(path string) int64 {
-	return darwin.GetDiskUsageAtPath(path)
+	return platform.DiskUsage(path)
 }
 
+// Reasons a directory is skipped during the walk
+const (
+	SkipDepthLimit = "depth limit"
+	SkipDomainHint = "domain hint"
+)
+
 // Decide if a directory should be skipped based on context
 func (f Finder) shouldSkipDir(name string, depth int, ctx ScanContext) bool {
+	return f.skipReason(name, depth, ctx) != ""
+}
+
+// Returns why a directory is skipped or "" if it is descended into
+func (f Finder) skipReason(name string, depth int, ctx ScanContext) string {
 	if depth > ctx.SearchDepth {
-		return true
+		return SkipDepthLimit
+	}
+	if (ctx.SearchDepth == STANDARD_DEPTH && depth < ctx.SearchDepth) && (ctx.DomainHint != "" && strings.Contains(name, ctx.DomainHint) && !f.isMatch(name, ctx)) {
+		return SkipDomainHint
 	}
-	if (ctx.SearchDepth == STANDARD_DEPTH && depth < ctx.SearchDepth) && (strings.Contains(name, ctx.DomainHint) && !f.isMatch(name, ctx)) {
-		return true
+	return ""
+}
+
+// Creates a match found under the context's search root
+func (ctx ScanContext) newMatch(path string, kind Kind, id Identifier, rule Rule) *Match {
+	return &Match{
+		Path:       path,
+		Root:       ctx.RootPath,
+		Kind:       kind,
+		Category:   ctx.Category,
+		Rule:       rule,
+		Identifier: id,
+		Confidence: scoreMatch(filepath.Base(path), rule, id, ctx),
+		index:      ctx.Index,
 	}
-	return false
 }
 
 // Helper function to print and send matches to channel
-func (f *Finder) emitMatch(name, path string, matchesChan chan string, opts options.Options, symlink bool) {
+func (f *Finder) emitMatch(name string, match *Match, matchesChan chan *Match, opts options.Options) {
 	if f.Reported {
-		matchesChan <- path
+		matchesChan <- match
 		return
 	}
 
-	if opts.Verbosity && !symlink {
-		log.Printf("Match %s FOUND at: %s", pfmt.ApplyColor(name, 2), pfmt.ApplyColor(path, 3))
-	} else if opts.Verbosity && symlink {
-		log.Printf("Symlink match %s FOUND at: %s", pfmt.ApplyColor(name, 2), pfmt.ApplyColor(path, 3))
+	if opts.Verbosity && !match.IsSymlink() {
+		log.Printf("Match %s FOUND at: %s (%s)", pfmt.ApplyColor(name, 2), pfmt.ApplyColor(match.Path, 3), match.Rule)
+	} else if opts.Verbosity && match.IsSymlink() {
+		log.Printf("Symlink match %s FOUND at: %s (%s)", pfmt.ApplyColor(name, 2), pfmt.ApplyColor(match.Path, 3), match.Rule)
 	}
 
-	matchesChan <- path
+	matchesChan <- match
 }
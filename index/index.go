@@ -0,0 +1,421 @@
+package index
+
+/*
+Index.go holds the logic for the persistent scan index which caches directory
+listings and sizes between runs so repeated scans only revisit what changed
+*/
+
+import (
+	"encoding/gob"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	VERSION   int           = 3              // bumped whenever the stored format changes
+	MAX_AGE   time.Duration = 24 * time.Hour // nodes older than this are rescanned even if unchanged
+	FILE_NAME string        = "index.gob"    // name of the index file in the cache directory
+)
+
+// DirReader lists the entries of a directory sorted by name
+type DirReader interface {
+	ReadDir(dir string) ([]fs.DirEntry, error)
+}
+
+// Reads directories straight from disk
+type osReader struct{}
+
+// OS is the DirReader reading every directory from disk
+var OS DirReader = osReader{}
+
+func (osReader) ReadDir(dir string) ([]fs.DirEntry, error) {
+	return os.ReadDir(dir)
+}
+
+// Node is the cached state of a single directory.
+//
+// A node's listing stays valid while the directory's mtime and inode are
+// unchanged, as adding, removing or renaming an entry always updates the
+// mtime. Files written in place do not, so their sizes are checked again
+type Node struct {
+	ModTime int64   // mtime of the directory in nanoseconds
+	Ino     uint64  // inode of the directory
+	Checked int64   // when the directory was last read in nanoseconds
+	Entries []Entry // entries of the directory sorted by name
+}
+
+// Entry is a cached directory entry
+type Entry struct {
+	Name    string
+	Mode    fs.FileMode // type bits of the entry
+	ModTime int64       // mtime of the entry in nanoseconds
+	Logical int64       // logical size of the entry
+	Disk    int64       // allocated size of the entry
+	Link    FileID      // device and inode of files with several hard links, zero otherwise
+}
+
+// FileID identifies a file across its hard links
+type FileID struct {
+	Dev uint64
+	Ino uint64
+}
+
+// Index is a persistent cache of directory nodes keyed by path
+type Index struct {
+	path   string
+	mu     sync.Mutex
+	nodes  map[string]*Node
+	values map[string]*Stamped
+	dirty  bool
+}
+
+// Layout of the index file
+type indexFile struct {
+	Version int
+	Nodes   map[string]*Node
+	Values  map[string]*Stamped
+}
+
+var (
+	defaultIndex *Index
+	defaultOnce  sync.Once
+)
+
+// Opens the index stored at path.
+//
+// A missing, unreadable or outdated index file starts an empty index
+func Open(path string) *Index {
+	ix := &Index{path: path, nodes: map[string]*Node{}, values: map[string]*Stamped{}}
+	file, err := os.Open(path)
+	if err != nil {
+		return ix
+	}
+	defer file.Close()
+
+	var stored indexFile
+	if err := gob.NewDecoder(file).Decode(&stored); err != nil || stored.Version != VERSION {
+		log.Printf("Discarding scan index %s", path)
+		return ix
+	}
+	ix.nodes = stored.Nodes
+	if stored.Values != nil {
+		ix.values = stored.Values
+	}
+	return ix
+}
+
+// Returns the index in the user cache directory, opened once per run.
+//
+// Falls back to an in memory index if the cache directory is unknown
+func Default() *Index {
+	defaultOnce.Do(func() {
+		path, err := DefaultPath()
+		if err != nil {
+			log.Printf("Scan index is not persisted: %v", err)
+		}
+		defaultIndex = Open(path)
+	})
+	return defaultIndex
+}
+
+// Returns the location of the index in the user cache directory
+func DefaultPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "rmapp", FILE_NAME), nil
+}
+
+// Saves the default index if it was used during this run
+func SaveDefault() {
+	if defaultIndex == nil {
+		return
+	}
+	if err := defaultIndex.Save(); err != nil {
+		log.Printf("Could not save scan index: %v", err)
+	}
+}
+
+// Writes the index to disk if anything changed since it was opened.
+//
+// Writes to a temporary file first so an interrupted save never
+// leaves a truncated index behind
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.dirty || ix.path == "" {
+		return nil
+	}
+
+	dir := filepath.Dir(ix.path)
+	_, statErr := os.Stat(dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if statErr != nil {
+		if err := chownToInvoker(dir); err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(dir, FILE_NAME+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(indexFile{Version: VERSION, Nodes: ix.nodes, Values: ix.values}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := chownToInvoker(tmp.Name()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), ix.path); err != nil {
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+// Hands path over to the user who invoked sudo, so an index saved in
+// their cache directory during an elevated run stays theirs
+func chownToInvoker(path string) error {
+	uid, err := strconv.Atoi(os.Getenv("SUDO_UID"))
+	if err != nil || os.Geteuid() != 0 {
+		return nil
+	}
+	gid, err := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if err != nil {
+		gid = -1
+	}
+	return os.Lchown(path, uid, gid)
+}
+
+// ReadDir returns the cached entries of dir, reading it from disk only
+// if it changed since it was cached
+func (ix *Index) ReadDir(dir string) ([]fs.DirEntry, error) {
+	node, err := ix.node(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(node.Entries))
+	for i, entry := range node.Entries {
+		entries[i] = dirEntry{dir: dir, entry: entry}
+	}
+	return entries, nil
+}
+
+// Size returns the logical or allocated size of path and everything below it.
+//
+// Sizes are summed from the cached nodes, so only directories that
+// changed since the last run are read again. Like du, the allocated size
+// counts files hard linked several times below path only once
+func (ix *Index) Size(path string, logical bool) int64 {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0
+	}
+	size := entrySize(info, logical)
+	if info.IsDir() {
+		size += ix.treeSize(path, logical, map[FileID]bool{})
+	}
+	return size
+}
+
+// Sums the sizes of every entry below dir, skipping the hard links in seen
+func (ix *Index) treeSize(dir string, logical bool, seen map[FileID]bool) int64 {
+	node, err := ix.node(dir)
+	if err != nil {
+		return 0
+	}
+	var size int64
+	for _, entry := range node.Entries {
+		if logical {
+			size += entry.Logical
+		} else if !seen[entry.Link] {
+			size += entry.Disk
+			if entry.Link != (FileID{}) {
+				seen[entry.Link] = true
+			}
+		}
+		if entry.Mode.IsDir() {
+			size += ix.treeSize(filepath.Join(dir, entry.Name), logical, seen)
+		}
+	}
+	return size
+}
+
+// Returns the node of dir, rescanning the directory if its mtime or inode
+// changed or the node is older than MAX_AGE
+func (ix *Index) node(dir string) (*Node, error) {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		ix.forget(dir)
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New(dir + " is not a directory")
+	}
+	ino, _ := statInfo(info)
+
+	ix.mu.Lock()
+	cached := ix.nodes[dir]
+	ix.mu.Unlock()
+	if cached != nil && cached.ModTime == info.ModTime().UnixNano() && cached.Ino == ino &&
+		time.Since(time.Unix(0, cached.Checked)) < MAX_AGE {
+		refreshed := refresh(dir, cached)
+		if refreshed != cached {
+			ix.mu.Lock()
+			ix.nodes[dir] = refreshed
+			ix.dirty = true
+			ix.mu.Unlock()
+		}
+		return refreshed, nil
+	}
+
+	node, err := scan(dir, info, ino)
+	if err != nil {
+		return nil, err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if cached != nil {
+		ix.forgetRemoved(dir, cached, node)
+	}
+	ix.nodes[dir] = node
+	ix.dirty = true
+	return node, nil
+}
+
+// Reads a directory from disk into a node
+func scan(dir string, info fs.FileInfo, ino uint64) (*Node, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &Node{
+		ModTime: info.ModTime().UnixNano(),
+		Ino:     ino,
+		Checked: time.Now().UnixNano(),
+		Entries: make([]Entry, 0, len(dirEntries)),
+	}
+	for _, dirEntry := range dirEntries {
+		entry := Entry{Name: dirEntry.Name(), Mode: dirEntry.Type()}
+		if entryInfo, err := dirEntry.Info(); err == nil {
+			entry = newEntry(entryInfo)
+		}
+		node.Entries = append(node.Entries, entry)
+	}
+	return node, nil
+}
+
+// Stats the regular files of a valid node again, as writing to a file in
+// place leaves the mtime of its directory unchanged.
+//
+// Returns node itself if no file changed, otherwise an updated copy
+func refresh(dir string, node *Node) *Node {
+	var entries []Entry
+	for i, entry := range node.Entries {
+		if !entry.Mode.IsRegular() {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(dir, entry.Name))
+		if err != nil || (info.ModTime().UnixNano() == entry.ModTime && info.Size() == entry.Logical) {
+			continue
+		}
+		if entries == nil {
+			entries = slices.Clone(node.Entries)
+		}
+		entries[i] = newEntry(info)
+	}
+	if entries == nil {
+		return node
+	}
+	refreshed := *node
+	refreshed.Entries = entries
+	return &refreshed
+}
+
+// Creates the cached entry of a file
+func newEntry(info fs.FileInfo) Entry {
+	entry := Entry{
+		Name:    info.Name(),
+		Mode:    info.Mode().Type(),
+		ModTime: info.ModTime().UnixNano(),
+		Logical: entrySize(info, true),
+		Disk:    entrySize(info, false),
+	}
+	if !info.IsDir() {
+		entry.Link = linkID(info)
+	}
+	return entry
+}
+
+// Drops the nodes below subdirectories that no longer exist.
+// Must be called with the lock held
+func (ix *Index) forgetRemoved(dir string, previous, current *Node) {
+	present := map[string]bool{}
+	for _, entry := range current.Entries {
+		if entry.Mode.IsDir() {
+			present[entry.Name] = true
+		}
+	}
+	for _, entry := range previous.Entries {
+		if entry.Mode.IsDir() && !present[entry.Name] {
+			ix.forgetLocked(filepath.Join(dir, entry.Name))
+		}
+	}
+}
+
+// Drops the node of dir and every node below it
+func (ix *Index) forget(dir string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.forgetLocked(dir)
+}
+
+// Drops the node of dir and every node below it.
+// Must be called with the lock held
+func (ix *Index) forgetLocked(dir string) {
+	prefix := dir + string(os.PathSeparator)
+	for path := range ix.nodes {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			delete(ix.nodes, path)
+			ix.dirty = true
+		}
+	}
+}
+
+// Returns the logical or allocated size of a single entry
+func entrySize(info fs.FileInfo, logical bool) int64 {
+	if logical {
+		return info.Size()
+	}
+	_, blocks := statInfo(info)
+	return blocks * 512
+}
+
+// dirEntry is a cached entry returned as an fs.DirEntry
+type dirEntry struct {
+	dir   string
+	entry Entry
+}
+
+func (d dirEntry) Name() string               { return d.entry.Name }
+func (d dirEntry) IsDir() bool                { return d.entry.Mode.IsDir() }
+func (d dirEntry) Type() fs.FileMode          { return d.entry.Mode }
+func (d dirEntry) Info() (fs.FileInfo, error) { return os.Lstat(filepath.Join(d.dir, d.entry.Name)) }
@@ -0,0 +1,240 @@
+package index
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alewtschuk/rmapp/platform"
+)
+
+// Creates files with the given contents below root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Moves the mtime of dir forward so the change is seen on filesystems with coarse timestamps
+func touch(t *testing.T, dir string) {
+	t.Helper()
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(dir, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Sums sizes the way the uncached logical size walk does
+func walkSize(root string) int64 {
+	var size int64
+	filepath.Walk(root, func(_ string, info fs.FileInfo, err error) error {
+		if err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+func TestReadDir(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a": "1", "b/c": "22"})
+	ix := Open(filepath.Join(t.TempDir(), FILE_NAME))
+
+	names := func() []string {
+		entries, err := ix.ReadDir(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	if got := names(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("Expected [a b], got %v", got)
+	}
+
+	// Listings stay cached until the directory changes
+	cached := ix.nodes[root]
+	names()
+	if ix.nodes[root] != cached {
+		t.Error("Expected unchanged directory to be served from the index")
+	}
+
+	writeFiles(t, root, map[string]string{"d": "333"})
+	touch(t, root)
+	if got := names(); len(got) != 3 {
+		t.Errorf("Expected new file to invalidate the listing, got %v", got)
+	}
+}
+
+func TestSize(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a": "1", "b/c": "22", "b/d/e": "333"})
+	ix := Open(filepath.Join(t.TempDir(), FILE_NAME))
+
+	if got, want := ix.Size(root, true), walkSize(root); got != want {
+		t.Errorf("Size = %d, want %d", got, want)
+	}
+
+	// Removing a folder updates the size and drops the nodes below it
+	if err := os.RemoveAll(filepath.Join(root, "b")); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, root)
+	if got, want := ix.Size(root, true), walkSize(root); got != want {
+		t.Errorf("Size after removal = %d, want %d", got, want)
+	}
+	if _, ok := ix.nodes[filepath.Join(root, "b", "d")]; ok {
+		t.Error("Expected nodes of removed folders to be dropped")
+	}
+
+	// Writing to a file in place leaves the folder's mtime alone but still updates the size
+	writeFiles(t, root, map[string]string{"a": "4444"})
+	if got, want := ix.Size(root, true), walkSize(root); got != want {
+		t.Errorf("Size after growing a file = %d, want %d", got, want)
+	}
+}
+
+func TestSizeHardLinks(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a": strings.Repeat("x", 64*1024), "b/c": "1"})
+	if err := os.Link(filepath.Join(root, "a"), filepath.Join(root, "b", "a")); err != nil {
+		t.Skip("Hard links are not supported:", err)
+	}
+	ix := Open(filepath.Join(t.TempDir(), FILE_NAME))
+
+	if got, want := ix.Size(root, false), platform.DiskUsage(root); got != want {
+		t.Errorf("Size = %d, want %d with the hard link counted once", got, want)
+	}
+}
+
+func TestSaveOpen(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a": "1"})
+	path := filepath.Join(t.TempDir(), "rmapp", FILE_NAME)
+
+	ix := Open(path)
+	ix.Size(root, true)
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := Open(path)
+	if _, ok := reopened.nodes[root]; !ok {
+		t.Error("Expected saved nodes to be loaded")
+	}
+
+	// An index saved under sudo belongs to the invoking user
+	if os.Geteuid() == 0 {
+		t.Setenv("SUDO_UID", "12345")
+		t.Setenv("SUDO_GID", "12345")
+		sudoPath := filepath.Join(t.TempDir(), "rmapp", FILE_NAME)
+		ix := Open(sudoPath)
+		ix.Size(root, true)
+		if err := ix.Save(); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{sudoPath, filepath.Dir(sudoPath)} {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Uid != 12345 {
+				t.Errorf("Expected %s to belong to the sudo user, owned by %d", path, stat.Uid)
+			}
+		}
+	}
+
+	// A corrupt index starts empty rather than failing
+	if err := os.WriteFile(path, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if len(Open(path).nodes) != 0 {
+		t.Error("Expected corrupt index to be discarded")
+	}
+}
+
+func TestFileValues(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"exec": "1"})
+	file := filepath.Join(root, "exec")
+	path := filepath.Join(t.TempDir(), FILE_NAME)
+
+	reads := 0
+	read := func() ([]string, error) {
+		reads++
+		return []string{"group"}, nil
+	}
+
+	ix := Open(path)
+	for range 2 {
+		if values, err := ix.FileValues(file, read); err != nil || len(values) != 1 || values[0] != "group" {
+			t.Fatalf("Expected cached values, got %v, %v", values, err)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("Expected an unchanged file to be read once, read %d times", reads)
+	}
+
+	// Saved values survive a reopen
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+	ix = Open(path)
+	ix.FileValues(file, read)
+	if reads != 1 {
+		t.Errorf("Expected saved values to be reused, read %d times", reads)
+	}
+
+	// A changed file is read again
+	writeFiles(t, root, map[string]string{"exec": "22"})
+	ix.FileValues(file, read)
+	if reads != 2 {
+		t.Errorf("Expected a changed file to be read again, read %d times", reads)
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a/x": "", "b/y": "", "c": ""})
+	ix := Open(filepath.Join(t.TempDir(), FILE_NAME))
+
+	var visited []string
+	err := WalkDir(ix, root, func(path string, d fs.DirEntry, err error) error {
+		rel, _ := filepath.Rel(root, path)
+		visited = append(visited, rel)
+		if d.IsDir() && d.Name() == "a" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{".", "a", "b", filepath.Join("b", "y"), "c"}
+	if len(visited) != len(want) {
+		t.Fatalf("Visited %v, want %v", visited, want)
+	}
+	for i := range want {
+		if visited[i] != want[i] {
+			t.Errorf("Visited %v, want %v", visited, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package index
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Returns the inode and the number of allocated 512 byte blocks of the entry
+func statInfo(info fs.FileInfo) (uint64, int64) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, (info.Size() + 511) / 512
+	}
+	return uint64(stat.Ino), int64(stat.Blocks)
+}
+
+// Returns the device and inode of a file with several hard links,
+// or a zero FileID if it has a single link
+func linkID(info fs.FileInfo) FileID {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink < 2 {
+		return FileID{}
+	}
+	return FileID{Dev: uint64(stat.Dev), Ino: uint64(stat.Ino)}
+}
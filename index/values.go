@@ -0,0 +1,47 @@
+package index
+
+/*
+Values.go holds the logic for caching values derived from single files,
+such as the groups declared by an app's code signature, until the file changes
+*/
+
+import "os"
+
+// Stamped is a cached value along with the state of the file it was derived from
+type Stamped struct {
+	ModTime int64  // mtime of the file in nanoseconds
+	Ino     uint64 // inode of the file
+	Size    int64  // logical size of the file
+	Values  []string
+}
+
+// FileValues returns the values derived from path, calling read only
+// if the file changed since they were cached.
+//
+// Files that cannot be stat'ed are read without caching, leaving read to
+// report the error
+func (ix *Index) FileValues(path string, read func() ([]string, error)) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return read()
+	}
+	ino, _ := statInfo(info)
+
+	ix.mu.Lock()
+	cached := ix.values[path]
+	ix.mu.Unlock()
+	if cached != nil && cached.ModTime == info.ModTime().UnixNano() && cached.Ino == ino && cached.Size == info.Size() {
+		return cached.Values, nil
+	}
+
+	values, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.values[path] = &Stamped{ModTime: info.ModTime().UnixNano(), Ino: ino, Size: info.Size(), Values: values}
+	ix.dirty = true
+	return values, nil
+}
@@ -0,0 +1,53 @@
+package index
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WalkDir walks the tree rooted at root like filepath.WalkDir, reading
+// every directory through r so cached listings are reused
+func WalkDir(r DirReader, root string, fn fs.WalkDirFunc) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(r, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// Calls fn on path and, for directories, recursively on every entry
+func walkDir(r DirReader, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil // successfully skipped directory
+		}
+		return err
+	}
+
+	entries, err := r.ReadDir(path)
+	if err != nil {
+		// Second call to report the ReadDir error
+		if err = fn(path, d, err); err != nil {
+			if err == fs.SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := walkDir(r, filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
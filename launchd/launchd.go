@@ -0,0 +1,164 @@
+// Package launchd reads launchd job definitions and unloads jobs through
+// launchctl, so apps are not removed while their agents and daemons still run.
+package launchd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alewtschuk/rmapp/plist"
+)
+
+// Folders launchd loads job definitions from
+const (
+	AgentsDir  = "LaunchAgents"
+	DaemonsDir = "LaunchDaemons"
+)
+
+// Exit code of launchctl bootout for jobs that are not loaded (ESRCH)
+const notLoadedExitCode = 3
+
+// Job is a launchd job definition
+type Job struct {
+	Label                       string   // unique job label
+	Program                     string   // Program, falling back to the first ProgramArguments entry
+	BundleProgram               string   // program path relative to the associated bundle, used by SMAppService
+	AssociatedBundleIdentifiers []string // bundles the job is shown under in Login Items
+	Path                        string   // path of the job's plist
+}
+
+// ReadJob reads the job defined by the plist at path
+func ReadJob(path string) (Job, error) {
+	dict, err := plist.ReadDict(path)
+	if err != nil {
+		return Job{}, err
+	}
+
+	job := Job{
+		Label:                       plist.String(dict, "Label"),
+		Program:                     plist.String(dict, "Program"),
+		BundleProgram:               plist.String(dict, "BundleProgram"),
+		AssociatedBundleIdentifiers: plist.Strings(dict, "AssociatedBundleIdentifiers"),
+		Path:                        path,
+	}
+	if args := plist.Strings(dict, "ProgramArguments"); job.Program == "" && len(args) > 0 {
+		job.Program = args[0]
+	}
+	// A single associated bundle may be stored as a plain string
+	if id := plist.String(dict, "AssociatedBundleIdentifiers"); id != "" {
+		job.AssociatedBundleIdentifiers = []string{id}
+	}
+	if job.Label == "" {
+		return Job{}, errors.New("launchd: job has no label")
+	}
+	return job, nil
+}
+
+// IsJobPath reports if path is a job plist inside a LaunchAgents or LaunchDaemons folder
+func IsJobPath(path string) bool {
+	dir := filepath.Base(filepath.Dir(path))
+	return strings.HasSuffix(path, ".plist") && (dir == AgentsDir || dir == DaemonsDir)
+}
+
+// RunsFrom reports if the job's program lives inside the bundle at bundlePath
+// or the job declares the bundle ID as its associated bundle
+func (j Job) RunsFrom(bundlePath, bundleID string) bool {
+	if bundlePath != "" {
+		program := filepath.Clean(j.Program)
+		if j.Program != "" && strings.HasPrefix(program, bundlePath+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	if bundleID != "" && j.BundleProgram != "" {
+		for _, id := range j.AssociatedBundleIdentifiers {
+			if strings.EqualFold(id, bundleID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Domain returns the launchctl service target of the job.
+//
+// Daemons live in the system domain, agents in the GUI domain of the user,
+// which is the user who invoked sudo when running elevated
+func (j Job) Domain() string {
+	if filepath.Base(filepath.Dir(j.Path)) == DaemonsDir {
+		return "system/" + j.Label
+	}
+	uid := os.Getuid()
+	if sudoUID, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil {
+		uid = sudoUID
+	}
+	return fmt.Sprintf("gui/%d/%s", uid, j.Label)
+}
+
+// Runner runs launchctl commands
+type Runner interface {
+	Run(args ...string) error
+}
+
+// Launchctl runs the launchctl binary
+type Launchctl struct{}
+
+// Runs launchctl with args, returning its output on failure
+func (Launchctl) Run(args ...string) error {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return &RunError{Args: args, Output: strings.TrimSpace(string(out)), Err: err}
+	}
+	return nil
+}
+
+// RunError is a failed launchctl invocation
+type RunError struct {
+	Args   []string
+	Output string
+	Err    error
+}
+
+func (e *RunError) Error() string {
+	if e.Output == "" {
+		return fmt.Sprintf("launchctl %s: %v", strings.Join(e.Args, " "), e.Err)
+	}
+	return fmt.Sprintf("launchctl %s: %v: %s", strings.Join(e.Args, " "), e.Err, e.Output)
+}
+
+func (e *RunError) Unwrap() error { return e.Err }
+
+// Bootout unloads the job so it stops running and is not relaunched.
+//
+// Jobs that are not loaded are not an error
+func Bootout(r Runner, job Job) error {
+	err := r.Run("bootout", job.Domain())
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == notLoadedExitCode {
+		return nil
+	}
+	return err
+}
+
+// Fake records launchctl invocations instead of running them. Used in tests
+type Fake struct {
+	mu    sync.Mutex
+	Calls [][]string       // arguments of every invocation in order
+	Errs  map[string]error // error returned for a service target
+}
+
+// Records the invocation and returns the error set for its last argument
+func (f *Fake) Run(args ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Calls = append(f.Calls, args)
+	if len(args) > 0 {
+		return f.Errs[args[len(args)-1]]
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package launchd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes a job plist with the given extra keys into dir
+func writeJob(t *testing.T, dir, label, keys string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, label+".plist")
+	data := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>Label</key><string>%s</string>
+%s
+</dict></plist>`, label, keys)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadJob(t *testing.T) {
+	root := t.TempDir()
+	agent := writeJob(t, filepath.Join(root, AgentsDir), "net.vendor.agent",
+		`<key>ProgramArguments</key><array><string>/Applications/Foo.app/Contents/MacOS/agent</string><string>--quiet</string></array>`)
+	daemon := writeJob(t, filepath.Join(root, DaemonsDir), "net.vendor.daemon",
+		`<key>BundleProgram</key><string>Contents/MacOS/daemon</string>
+<key>AssociatedBundleIdentifiers</key><string>com.vendor.foo</string>`)
+
+	job, err := ReadJob(agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Program != "/Applications/Foo.app/Contents/MacOS/agent" {
+		t.Errorf("Expected program from ProgramArguments, got %q", job.Program)
+	}
+	if !job.RunsFrom("/Applications/Foo.app", "") || job.RunsFrom("/Applications/Foo Beta.app", "") || job.RunsFrom("/Applications/Fo", "") {
+		t.Error("Expected job to run only from its own bundle")
+	}
+	if want := fmt.Sprintf("gui/%d/net.vendor.agent", os.Getuid()); job.Domain() != want && os.Getenv("SUDO_UID") == "" {
+		t.Errorf("Domain = %s, want %s", job.Domain(), want)
+	}
+
+	job, err = ReadJob(daemon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !job.RunsFrom("", "com.vendor.foo") || job.RunsFrom("", "com.vendor.bar") {
+		t.Error("Expected job to run from its associated bundle")
+	}
+	if job.Domain() != "system/net.vendor.daemon" {
+		t.Errorf("Domain = %s, want system/net.vendor.daemon", job.Domain())
+	}
+
+	if !IsJobPath(agent) || IsJobPath(filepath.Join(root, "Preferences", "net.vendor.agent.plist")) {
+		t.Error("Expected only plists in launchd folders to be job paths")
+	}
+}
+
+func TestBootout(t *testing.T) {
+	failure := errors.New("permission denied")
+	fake := &Fake{Errs: map[string]error{"system/net.vendor.daemon": failure}}
+
+	if err := Bootout(fake, Job{Label: "net.vendor.agent", Path: "/Library/LaunchAgents/net.vendor.agent.plist"}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := Bootout(fake, Job{Label: "net.vendor.daemon", Path: "/Library/LaunchDaemons/net.vendor.daemon.plist"}); !errors.Is(err, failure) {
+		t.Errorf("Expected runner error, got %v", err)
+	}
+	if len(fake.Calls) != 2 || fake.Calls[1][0] != "bootout" || fake.Calls[1][1] != "system/net.vendor.daemon" {
+		t.Errorf("Unexpected launchctl calls %v", fake.Calls)
+	}
+}
@@ -2,10 +2,22 @@ package options
 
 // Holds all command line related options
 type Options struct {
-	Verbosity  bool // is verbose flag set
-	Mode       bool // sets mode between trash and delete
-	Peek       bool // sets user peeking files to true
-	Logical    bool // sets whether the user wants logical or native disk usage size
-	Size       bool // sets if the user just wants to view application size
-	BundleOnly bool // sets if only the main application bundle is set to be removed
+	Verbosity   bool   // is verbose flag set
+	Mode        bool   // sets mode between trash and delete
+	Peek        bool   // sets user peeking files to true
+	Logical     bool   // sets whether the user wants logical or native disk usage size
+	Size        bool   // sets if the user just wants to view application size
+	BundleOnly  bool   // sets if only the main application bundle is set to be removed
+	BundleID    string // explicit bundle ID to search for, used when the .app is already gone
+	AppDepth    int    // max folder depth searched for .app bundles, 0 uses the default
+	NoCache     bool   // bypasses the persistent scan index and reads everything from disk
+	Kill        bool   // quits running app processes without asking
+	Trash       string // trash backend to use, "" picks the platform's default
+	Platform    string // platform whose app and data layout is searched, "" uses the running one
+	Root        string // directory every searched path is rebased onto, "" searches the running system
+	Home        string // home directory searched as seen inside Root, "" uses $HOME
+	AllowDelete bool   // allows removing files found under Root, which is read only otherwise
+	Output      string // machine readable format results are written in, "" prints text
+
+	MinConfidence float64 // matches scoring below are dropped entirely
 }
@@ -0,0 +1,211 @@
+// Package output writes the results of peeking, sizing and removing an app
+// as versioned JSON documents or newline delimited JSON events, so they can
+// be consumed by scripts instead of scraping the colored text output.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/alewtschuk/rmapp/deleter"
+	"github.com/alewtschuk/rmapp/finder"
+)
+
+// SCHEMA_VERSION is raised whenever a field changes meaning or is removed
+const SCHEMA_VERSION int = 1
+
+// Output formats
+const (
+	TEXT   string = "text"   // colored human readable output
+	JSON   string = "json"   // one document once the run completed
+	NDJSON string = "ndjson" // one event per line as soon as it is known
+)
+
+// Kinds of report
+const (
+	KindPeek   string = "peek"   // matches listed with --peek
+	KindSize   string = "size"   // matches sized with --size
+	KindRemove string = "remove" // matches trashed or deleted
+)
+
+// Events of an NDJSON stream, in the order they are written
+const (
+	EventStart  string = "start"
+	EventMatch  string = "match"
+	EventResult string = "result"
+	EventEnd    string = "end"
+)
+
+// Report is the JSON document of a whole run
+type Report struct {
+	Schema   int      `json:"schema"`
+	Kind     string   `json:"kind"`
+	App      string   `json:"app"`
+	BundleID string   `json:"bundle_id,omitempty"`
+	Run      string   `json:"run,omitempty"` // ID to pass to rmapp restore when files were trashed
+	Matches  []Match  `json:"matches"`
+	Results  []Result `json:"results,omitempty"`
+	Totals   Totals   `json:"totals"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Match is a file or folder found for the app
+type Match struct {
+	Path       string  `json:"path"`
+	Kind       string  `json:"kind"`
+	Category   string  `json:"category"`
+	Size       int64   `json:"size"` // disk usage in bytes, logical size with --logical
+	Symlink    bool    `json:"symlink"`
+	Confidence float64 `json:"confidence"`
+	Rule       string  `json:"rule"`
+	Owner      string  `json:"owner,omitempty"` // owner declared in container metadata
+}
+
+// Result is the outcome of removing a single match
+type Result struct {
+	Path    string `json:"path"`
+	Outcome string `json:"outcome"`
+	Trashed string `json:"trashed,omitempty"` // where the match ended up in the Trash
+	Freed   int64  `json:"freed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Totals sums up the matches and, for removals, the results
+type Totals struct {
+	Files  int   `json:"files"`
+	Size   int64 `json:"size"`
+	Freed  int64 `json:"freed"`
+	Failed int   `json:"failed"`
+}
+
+// Event is a single line of an NDJSON stream
+type Event struct {
+	Schema   int     `json:"schema"`
+	Event    string  `json:"event"`
+	Kind     string  `json:"kind,omitempty"`
+	App      string  `json:"app,omitempty"`
+	BundleID string  `json:"bundle_id,omitempty"`
+	Match    *Match  `json:"match,omitempty"`
+	Result   *Result `json:"result,omitempty"`
+	Run      string  `json:"run,omitempty"`
+	Totals   *Totals `json:"totals,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Checks the format passed on the command line
+func Valid(format string) error {
+	if format == TEXT || format == JSON || format == NDJSON {
+		return nil
+	}
+	return fmt.Errorf("unknown output %q, use %s, %s or %s", format, TEXT, JSON, NDJSON)
+}
+
+// Writer builds the report of a run and writes it in its format.
+//
+// JSON reports are written as a single document on Close, NDJSON
+// reports as an event per match and result as they are added
+type Writer struct {
+	format  string
+	out     *json.Encoder
+	report  Report
+	mu      sync.Mutex
+	written error // first error writing to out
+}
+
+// Creates a writer for a report of kind and starts the NDJSON stream
+func NewWriter(out io.Writer, format, kind, app, bundleID string) *Writer {
+	w := &Writer{
+		format: format,
+		out:    json.NewEncoder(out),
+		report: Report{Schema: SCHEMA_VERSION, Kind: kind, App: app, BundleID: bundleID, Matches: []Match{}},
+	}
+	if format == JSON {
+		w.out.SetIndent("", "  ")
+	}
+	w.event(Event{Event: EventStart, Kind: kind, App: app, BundleID: bundleID})
+	return w
+}
+
+// Adds the matches, reading their logical sizes when logical is set
+func (w *Writer) Matches(matches []*finder.Match, logical bool) {
+	for _, match := range matches {
+		m := Match{
+			Path:       match.Path,
+			Kind:       string(match.Kind),
+			Category:   match.Category,
+			Size:       match.Size(logical),
+			Symlink:    match.IsSymlink(),
+			Confidence: match.Confidence,
+			Rule:       string(match.Rule),
+			Owner:      match.Owner,
+		}
+		w.mu.Lock()
+		w.report.Matches = append(w.report.Matches, m)
+		w.event(Event{Event: EventMatch, Match: &m})
+		w.mu.Unlock()
+	}
+}
+
+// Adds the outcome of removing a match. Safe to pass to Deleter.OnResult
+func (w *Writer) Result(result deleter.Result) {
+	r := Result{
+		Path:    result.Match.Path,
+		Outcome: result.Outcome,
+		Trashed: result.Trashed,
+		Freed:   result.Freed(),
+	}
+	if result.Err != nil {
+		r.Error = result.Err.Error()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.report.Results = append(w.report.Results, r)
+	w.event(Event{Event: EventResult, Result: &r})
+}
+
+// Sums up the run and writes the JSON document or the end of the NDJSON stream.
+//
+// run is the restore ID of trashed files and err the error the run failed with
+func (w *Writer) Close(run string, err error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	totals := Totals{Files: len(w.report.Matches)}
+	for _, match := range w.report.Matches {
+		totals.Size += match.Size
+	}
+	for _, result := range w.report.Results {
+		totals.Freed += result.Freed
+		if result.Outcome == deleter.OutcomeFailed {
+			totals.Failed++
+		}
+	}
+	w.report.Totals = totals
+	w.report.Run = run
+	if err != nil {
+		w.report.Error = err.Error()
+	}
+
+	if w.format == JSON {
+		if encodeErr := w.out.Encode(w.report); w.written == nil {
+			w.written = encodeErr
+		}
+		return w.written
+	}
+	w.event(Event{Event: EventEnd, Run: run, Totals: &totals, Error: w.report.Error})
+	return w.written
+}
+
+// Writes an event of the NDJSON stream, doing nothing for other formats
+func (w *Writer) event(event Event) {
+	if w.format != NDJSON {
+		return
+	}
+	event.Schema = SCHEMA_VERSION
+	if err := w.out.Encode(event); err != nil && w.written == nil {
+		w.written = err
+	}
+}
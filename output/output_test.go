@@ -0,0 +1,105 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alewtschuk/rmapp/deleter"
+	"github.com/alewtschuk/rmapp/finder"
+)
+
+// Creates a file of size bytes and returns its match
+func testMatch(t *testing.T, name string, size int) *finder.Match {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	match := finder.NewMatch(path)
+	match.Category = "Caches"
+	return match
+}
+
+func TestJSON(t *testing.T) {
+	deleted := testMatch(t, "deleted", 4096)
+	failed := testMatch(t, "failed", 10)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, JSON, KindRemove, "Test", "com.gemini.test")
+	w.Matches([]*finder.Match{deleted, failed}, true)
+	w.Result(deleter.Result{Match: deleted, Outcome: deleter.OutcomeDeleted})
+	w.Result(deleter.Result{Match: failed, Outcome: deleter.OutcomeFailed, Err: errors.New("permission denied")})
+	if err := w.Close("", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Expected a single JSON document, got %v:\n%s", err, buf.String())
+	}
+	if report.Schema != SCHEMA_VERSION || report.Kind != KindRemove || report.BundleID != "com.gemini.test" {
+		t.Errorf("Unexpected report header %+v", report)
+	}
+	if len(report.Matches) != 2 || report.Matches[0].Size != 4096 || report.Matches[0].Category != "Caches" {
+		t.Errorf("Unexpected matches %+v", report.Matches)
+	}
+	if len(report.Results) != 2 || report.Results[1].Error != "permission denied" {
+		t.Errorf("Unexpected results %+v", report.Results)
+	}
+	if report.Totals.Files != 2 || report.Totals.Size != 4106 || report.Totals.Failed != 1 || report.Totals.Freed != deleted.Size(false) {
+		t.Errorf("Unexpected totals %+v", report.Totals)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	match := testMatch(t, "trashed", 10)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, NDJSON, KindRemove, "Test", "")
+	w.Matches([]*finder.Match{match}, false)
+	w.Result(deleter.Result{Match: match, Outcome: deleter.OutcomeTrashed, Trashed: "/trash/trashed"})
+	if err := w.Close("run-1", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Expected an event per line, got %v: %s", err, scanner.Text())
+		}
+		events = append(events, event)
+	}
+
+	order := []string{EventStart, EventMatch, EventResult, EventEnd}
+	if len(events) != len(order) {
+		t.Fatalf("Expected %d events, got %d", len(order), len(events))
+	}
+	for i, event := range events {
+		if event.Event != order[i] || event.Schema != SCHEMA_VERSION {
+			t.Errorf("Expected event %d to be %s, got %+v", i, order[i], event)
+		}
+	}
+	if events[2].Result.Trashed != "/trash/trashed" || events[2].Result.Outcome != deleter.OutcomeTrashed {
+		t.Errorf("Unexpected result %+v", events[2].Result)
+	}
+	if end := events[3]; end.Run != "run-1" || end.Totals.Files != 1 {
+		t.Errorf("Unexpected end %+v", end)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, format := range []string{TEXT, JSON, NDJSON} {
+		if err := Valid(format); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", format, err)
+		}
+	}
+	if Valid("yaml") == nil {
+		t.Error("Expected yaml to be invalid")
+	}
+}
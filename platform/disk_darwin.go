@@ -0,0 +1,10 @@
+//go:build darwin && cgo
+
+package platform
+
+import "github.com/alewtschuk/rmapp/darwin"
+
+// DiskUsage returns the space allocated on disk for the file or folder at path in bytes
+func DiskUsage(path string) int64 {
+	return darwin.GetDiskUsageAtPath(path)
+}
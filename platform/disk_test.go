@@ -0,0 +1,30 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	if err := os.WriteFile(data, make([]byte, 64*1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	single := DiskUsage(data)
+	if single < 64*1024 {
+		t.Fatalf("DiskUsage(%s) = %d, want at least %d", data, single, 64*1024)
+	}
+
+	withDir := DiskUsage(dir)
+	if err := os.Link(data, filepath.Join(dir, "hardlink")); err != nil {
+		t.Skip("hard links not supported:", err)
+	}
+	if linked := DiskUsage(dir); linked != withDir {
+		t.Errorf("Expected a hard link to add nothing, got %d instead of %d", linked, withDir)
+	}
+	if missing := DiskUsage(filepath.Join(dir, "missing")); missing != 0 {
+		t.Errorf("Expected 0 for a missing path, got %d", missing)
+	}
+}
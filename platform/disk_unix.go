@@ -0,0 +1,49 @@
+//go:build unix && !(darwin && cgo)
+
+package platform
+
+import (
+	"io/fs"
+	"path/filepath"
+	"syscall"
+)
+
+// Identifies a file across hard links
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// DiskUsage returns the space allocated on disk for the file or folder at path in bytes.
+//
+// Sums the 512 byte blocks of every entry like du, counting files
+// hard linked several times below path only once
+func DiskUsage(path string) int64 {
+	var total int64
+	seen := map[fileID]bool{}
+
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			total += info.Size()
+			return nil
+		}
+		if !info.IsDir() && stat.Nlink > 1 {
+			id := fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}
+			if seen[id] {
+				return nil
+			}
+			seen[id] = true
+		}
+		total += int64(stat.Blocks) * 512
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,6 @@
+// Package platform wraps the operating system specific parts of rmapp.
+//
+// On macOS built with cgo the native APIs of the darwin package are used,
+// everywhere else pure Go implementations built on the syscall package, so
+// the finder and deleter build and run on any unix.
+package platform
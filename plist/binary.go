@@ -0,0 +1,268 @@
+package plist
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	binaryMagic       = "bplist00"
+	binaryTrailerSize = 32
+	maxNestingDepth   = 512
+)
+
+// Seconds between the Unix epoch and the Core Data epoch (2001-01-01 UTC)
+const coreDataEpoch = 978307200
+
+// Holds the state needed to decode a binary property list
+type binaryDecoder struct {
+	data          []byte
+	offsets       []uint64
+	objectRefSize int
+}
+
+// Decodes a binary (bplist00) property list
+func decodeBinary(data []byte) (any, error) {
+	if len(data) < len(binaryMagic)+binaryTrailerSize {
+		return nil, ErrMalformed
+	}
+
+	trailer := data[len(data)-binaryTrailerSize:]
+	offsetIntSize := int(trailer[6])
+	objectRefSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	offsetTableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetIntSize < 1 || offsetIntSize > 8 || objectRefSize < 1 || objectRefSize > 8 {
+		return nil, ErrMalformed
+	}
+	tableEnd := uint64(len(data) - binaryTrailerSize)
+	if numObjects == 0 || topObject >= numObjects || offsetTableOffset > tableEnd ||
+		numObjects > (tableEnd-offsetTableOffset)/uint64(offsetIntSize) {
+		return nil, ErrMalformed
+	}
+
+	d := &binaryDecoder{
+		data:          data,
+		offsets:       make([]uint64, numObjects),
+		objectRefSize: objectRefSize,
+	}
+	for i := range d.offsets {
+		start := offsetTableOffset + uint64(i*offsetIntSize)
+		d.offsets[i] = readUint(data[start : start+uint64(offsetIntSize)])
+	}
+
+	return d.object(topObject, 0)
+}
+
+// Decodes the object with the given reference
+func (d *binaryDecoder) object(ref uint64, depth int) (any, error) {
+	if depth > maxNestingDepth {
+		return nil, fmt.Errorf("plist: nesting deeper than %d", maxNestingDepth)
+	}
+	if ref >= uint64(len(d.offsets)) {
+		return nil, ErrMalformed
+	}
+	off := d.offsets[ref]
+	if off >= uint64(len(d.data)) {
+		return nil, ErrMalformed
+	}
+
+	marker := d.data[off]
+	kind, info := marker>>4, marker&0x0f
+	off++
+
+	switch kind {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		return d.integer(off, 1<<info)
+	case 0x2:
+		b, err := d.bytes(off, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		switch len(b) {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+		}
+		return nil, ErrMalformed
+	case 0x3:
+		b, err := d.bytes(off, 8)
+		if err != nil {
+			return nil, err
+		}
+		secs := math.Float64frombits(binary.BigEndian.Uint64(b))
+		whole, frac := math.Modf(secs)
+		return time.Unix(coreDataEpoch+int64(whole), int64(frac*1e9)).UTC(), nil
+	case 0x4, 0x5, 0x6:
+		n, start, err := d.length(info, off)
+		if err != nil {
+			return nil, err
+		}
+		if kind == 0x6 {
+			return d.utf16String(start, n)
+		}
+		b, err := d.bytes(start, n)
+		if err != nil {
+			return nil, err
+		}
+		if kind == 0x5 {
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case 0x8:
+		return d.integer(off, uint64(info)+1)
+	case 0xA, 0xC:
+		n, start, err := d.length(info, off)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(start, n)
+		if err != nil {
+			return nil, err
+		}
+		arr := make([]any, 0, len(refs))
+		for _, r := range refs {
+			v, err := d.object(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case 0xD:
+		n, start, err := d.length(info, off)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(start, 2*n)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]any, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("plist: dict key is %T, not string", k)
+			}
+			v, err := d.object(refs[n+i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		}
+		return dict, nil
+	}
+
+	return nil, fmt.Errorf("plist: unknown object marker 0x%02x", marker)
+}
+
+// Returns the byte slice [off, off+n) after bounds checking
+func (d *binaryDecoder) bytes(off, n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) || off > uint64(len(d.data))-n {
+		return nil, ErrMalformed
+	}
+	return d.data[off : off+n], nil
+}
+
+// Decodes a big-endian integer of n bytes at off
+func (d *binaryDecoder) integer(off, n uint64) (any, error) {
+	b, err := d.bytes(off, n)
+	if err != nil {
+		return nil, err
+	}
+	switch n {
+	case 1, 2, 4:
+		return int64(readUint(b)), nil
+	case 8:
+		return int64(binary.BigEndian.Uint64(b)), nil
+	case 16:
+		// 128 bit integers only appear for values above math.MaxInt64
+		return binary.BigEndian.Uint64(b[8:]), nil
+	}
+	return nil, ErrMalformed
+}
+
+// Resolves the element count of a collection or string object and
+// returns it along with the offset its payload starts at
+func (d *binaryDecoder) length(info byte, off uint64) (uint64, uint64, error) {
+	if info != 0x0f {
+		return uint64(info), off, nil
+	}
+	b, err := d.bytes(off, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if b[0]>>4 != 0x1 {
+		return 0, 0, ErrMalformed
+	}
+	size := uint64(1) << (b[0] & 0x0f)
+	v, err := d.integer(off+1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, ok := v.(int64)
+	if !ok || n < 0 {
+		return 0, 0, ErrMalformed
+	}
+	return uint64(n), off + 1 + size, nil
+}
+
+// Reads n object references starting at off
+func (d *binaryDecoder) refs(off, n uint64) ([]uint64, error) {
+	size := uint64(d.objectRefSize)
+	if n > uint64(len(d.data))/size {
+		return nil, ErrMalformed
+	}
+	b, err := d.bytes(off, n*size)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, n)
+	for i := range refs {
+		refs[i] = readUint(b[uint64(i)*size : uint64(i+1)*size])
+	}
+	return refs, nil
+}
+
+// Decodes a big-endian UTF-16 string of n code units at off
+func (d *binaryDecoder) utf16String(off, n uint64) (string, error) {
+	if n > uint64(len(d.data))/2 {
+		return "", ErrMalformed
+	}
+	b, err := d.bytes(off, 2*n)
+	if err != nil {
+		return "", err
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// Reads an unsigned big-endian integer of up to 8 bytes
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
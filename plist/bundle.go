@@ -0,0 +1,66 @@
+package plist
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// BundleInfo holds the identifying metadata of a bundle's Info.plist
+type BundleInfo struct {
+	Identifier   string // CFBundleIdentifier
+	Name         string // CFBundleName
+	DisplayName  string // CFBundleDisplayName
+	Executable   string // CFBundleExecutable
+	ShortVersion string // CFBundleShortVersionString
+	Version      string // CFBundleVersion
+
+	PrivilegedExecutables []string // labels of SMPrivilegedExecutables helper tools
+}
+
+// ReadBundleInfo reads the Info.plist of the bundle at bundlePath.
+//
+// Checks the standard Contents/Info.plist location first and falls
+// back to a shallow bundle layout with Info.plist at the root
+func ReadBundleInfo(bundlePath string) (BundleInfo, error) {
+	var lastErr error
+	for _, rel := range []string{"Contents/Info.plist", "Info.plist"} {
+		dict, err := ReadDict(filepath.Join(bundlePath, rel))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return bundleInfoFromDict(dict), nil
+	}
+	if errors.Is(lastErr, os.ErrNotExist) {
+		return BundleInfo{}, errors.New("plist: no Info.plist found in " + bundlePath)
+	}
+	return BundleInfo{}, lastErr
+}
+
+// ParseBundleInfo decodes an Info.plist held in data
+func ParseBundleInfo(data []byte) (BundleInfo, error) {
+	root, err := Decode(data)
+	if err != nil {
+		return BundleInfo{}, err
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return BundleInfo{}, errors.New("plist: root object is not a dictionary")
+	}
+	return bundleInfoFromDict(dict), nil
+}
+
+// Extracts the bundle keys out of a decoded Info.plist dictionary
+func bundleInfoFromDict(dict map[string]any) BundleInfo {
+	return BundleInfo{
+		Identifier:   String(dict, "CFBundleIdentifier"),
+		Name:         String(dict, "CFBundleName"),
+		DisplayName:  String(dict, "CFBundleDisplayName"),
+		Executable:   String(dict, "CFBundleExecutable"),
+		ShortVersion: String(dict, "CFBundleShortVersionString"),
+		Version:      String(dict, "CFBundleVersion"),
+
+		PrivilegedExecutables: Keys(dict, "SMPrivilegedExecutables"),
+	}
+}
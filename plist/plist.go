@@ -0,0 +1,90 @@
+// Package plist decodes XML and binary (bplist00) property lists into plain
+// Go values without relying on any macOS tooling.
+//
+// Decoded values use the following Go types:
+//
+//	dict    map[string]any
+//	array   []any
+//	string  string
+//	integer int64 (uint64 when the value does not fit)
+//	real    float64
+//	boolean bool
+//	date    time.Time
+//	data    []byte
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sort"
+)
+
+// Errors returned while decoding a property list
+var (
+	ErrUnknownFormat = errors.New("plist: unknown property list format")
+	ErrMalformed     = errors.New("plist: malformed property list")
+)
+
+// Decode detects the format of data and returns the decoded root object
+func Decode(data []byte) (any, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte(binaryMagic)):
+		return decodeBinary(data)
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")):
+		return decodeXML(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ReadFile reads and decodes the property list at path
+func ReadFile(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// ReadDict reads the property list at path and ensures its root object is a dictionary
+func ReadDict(path string) (map[string]any, error) {
+	root, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := root.(map[string]any)
+	if !ok {
+		return nil, errors.New("plist: root object is not a dictionary")
+	}
+	return dict, nil
+}
+
+// String returns the string stored at key in dict or "" if absent or not a string
+func String(dict map[string]any, key string) string {
+	s, _ := dict[key].(string)
+	return s
+}
+
+// Strings returns the string elements of the array stored at key in dict
+func Strings(dict map[string]any, key string) []string {
+	arr, _ := dict[key].([]any)
+	var strs []string
+	for _, v := range arr {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// Keys returns the sorted keys of the dictionary stored at key in dict
+func Keys(dict map[string]any, key string) []string {
+	sub, _ := dict[key].(map[string]any)
+	keys := make([]string, 0, len(sub))
+	for k := range sub {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+package plist
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeFixtures(t *testing.T) {
+	for _, fixture := range []string{"Info.xml.plist", "Info.binary.plist"} {
+		t.Run(fixture, func(t *testing.T) {
+			dict, err := ReadDict(filepath.Join("testdata", fixture))
+			if err != nil {
+				t.Fatalf("ReadDict(%s) failed: %v", fixture, err)
+			}
+
+			if got := String(dict, "CFBundleDisplayName"); got != "Slack – Team Chat" {
+				t.Errorf("CFBundleDisplayName = %q", got)
+			}
+			if got := dict["NSHighResolutionCapable"]; got != true {
+				t.Errorf("NSHighResolutionCapable = %v, want true", got)
+			}
+			if got := dict["LSRequiresNativeExecution"]; got != false {
+				t.Errorf("LSRequiresNativeExecution = %v, want false", got)
+			}
+			if got := dict["Count"]; got != int64(300) {
+				t.Errorf("Count = %#v, want 300", got)
+			}
+			if got := dict["Negative"]; got != int64(-42) {
+				t.Errorf("Negative = %#v, want -42", got)
+			}
+			if got := dict["Big"]; got != uint64(1<<63+5) {
+				t.Errorf("Big = %#v, want %d", got, uint64(1<<63+5))
+			}
+			if got := dict["Ratio"]; got != 1.5 {
+				t.Errorf("Ratio = %#v, want 1.5", got)
+			}
+			if got, _ := dict["Built"].(time.Time); !got.Equal(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)) {
+				t.Errorf("Built = %v", dict["Built"])
+			}
+			if got, _ := dict["Blob"].([]byte); !bytes.Equal(got, []byte("\x00\x01rmapp")) {
+				t.Errorf("Blob = %v", dict["Blob"])
+			}
+
+			urlTypes, _ := dict["CFBundleURLTypes"].([]any)
+			if len(urlTypes) != 1 {
+				t.Fatalf("CFBundleURLTypes = %v", dict["CFBundleURLTypes"])
+			}
+			schemes := Strings(urlTypes[0].(map[string]any), "CFBundleURLSchemes")
+			if len(schemes) != 1 || schemes[0] != "slack" {
+				t.Errorf("CFBundleURLSchemes = %v", schemes)
+			}
+		})
+	}
+}
+
+func TestReadBundleInfo(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "Info.binary.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(t.TempDir(), "Slack.app")
+	if err := os.MkdirAll(filepath.Join(bundle, "Contents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bundle, "Contents", "Info.plist"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ReadBundleInfo(bundle)
+	if err != nil {
+		t.Fatalf("ReadBundleInfo failed: %v", err)
+	}
+	want := BundleInfo{
+		Identifier:   "com.tinyspeck.slackmacgap",
+		Name:         "Slack",
+		DisplayName:  "Slack – Team Chat",
+		Executable:   "Slack",
+		ShortVersion: "4.41.105",
+		Version:      "441105",
+
+		PrivilegedExecutables: []string{"com.tinyspeck.slackmacgap.helper"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("ReadBundleInfo = %+v, want %+v", info, want)
+	}
+
+	if _, err := ReadBundleInfo(t.TempDir()); err == nil {
+		t.Error("expected error for directory without Info.plist")
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "Info.binary.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := map[string][]byte{
+		"empty":     {},
+		"text":      []byte("not a plist"),
+		"truncated": data[:len(data)/2],
+		"xml":       []byte("<plist><dict><key>a</key>"),
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(input); err == nil {
+				t.Errorf("Decode(%s) succeeded, want error", name)
+			}
+		})
+	}
+}
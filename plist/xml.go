@@ -0,0 +1,171 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decodes an XML property list
+func decodeXML(data []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	// Seek to the first value element, skipping the <plist> wrapper and prolog
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, ErrMalformed
+			}
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local == "plist" {
+			continue
+		}
+		return decodeXMLValue(dec, start)
+	}
+}
+
+// Decodes the value element opened by start, consuming its end element
+func decodeXMLValue(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		return decodeXMLDict(dec)
+	case "array":
+		return decodeXMLArray(dec)
+	case "true", "false":
+		if err := dec.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := xmlText(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+	case "integer":
+		text = strings.TrimSpace(text)
+		if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return i, nil
+		}
+		u, err := strconv.ParseUint(text, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid integer %q", text)
+		}
+		return u, nil
+	case "real":
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid real %q", text)
+		}
+		return f, nil
+	case "date":
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid date %q", text)
+		}
+		return t, nil
+	case "data":
+		clean := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+				return -1
+			}
+			return r
+		}, text)
+		b, err := base64.StdEncoding.DecodeString(clean)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid data: %w", err)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("plist: unexpected element <%s>", start.Name.Local)
+	}
+}
+
+// Decodes <key>/value pairs until the closing </dict>
+func decodeXMLDict(dec *xml.Decoder) (any, error) {
+	dict := map[string]any{}
+	var key string
+	hasKey := false
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "key" {
+				key, err = xmlText(dec)
+				if err != nil {
+					return nil, err
+				}
+				hasKey = true
+				continue
+			}
+			if !hasKey {
+				return nil, fmt.Errorf("plist: dict value <%s> without key", t.Name.Local)
+			}
+			value, err := decodeXMLValue(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = value
+			hasKey = false
+		case xml.EndElement:
+			return dict, nil
+		}
+	}
+}
+
+// Decodes values until the closing </array>
+func decodeXMLArray(dec *xml.Decoder) (any, error) {
+	arr := []any{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			value, err := decodeXMLValue(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		case xml.EndElement:
+			return arr, nil
+		}
+	}
+}
+
+// Collects the character data of the current element up to its end element
+func xmlText(dec *xml.Decoder) (string, error) {
+	var sb strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", ErrMalformed
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			return sb.String(), nil
+		case xml.StartElement:
+			return "", fmt.Errorf("plist: unexpected element <%s> in text", t.Name.Local)
+		}
+	}
+}
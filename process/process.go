@@ -0,0 +1,212 @@
+// Package process finds running processes by the location of their executable
+// and quits them, so apps are not removed while they are still running.
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Interval between checks for processes that were asked to quit
+const pollInterval = 100 * time.Millisecond
+
+// Process is a running process
+type Process struct {
+	PID        int    // process ID
+	Executable string // full path of the executable
+}
+
+// Returns the process name for display
+func (p Process) Name() string {
+	return filepath.Base(p.Executable)
+}
+
+// Source lists running processes and sends them signals
+type Source interface {
+	List() ([]Process, error)
+	Signal(pid int, sig syscall.Signal) error
+}
+
+// Default returns the source for the current platform
+func Default() Source {
+	if runtime.GOOS == "linux" {
+		return Proc{Root: "/proc"}
+	}
+	return PS{}
+}
+
+// PS lists processes through ps, which reports the full executable path on macOS
+type PS struct{}
+
+func (PS) List() ([]Process, error) {
+	out, err := exec.Command("ps", "-axo", "pid=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []Process
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		pidStr, comm, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, Process{PID: pid, Executable: strings.TrimSpace(comm)})
+	}
+	return procs, scanner.Err()
+}
+
+func (PS) Signal(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+// Proc lists processes through the exe links of a Linux procfs mounted at Root
+type Proc struct {
+	Root string
+}
+
+func (p Proc) List() ([]Process, error) {
+	entries, err := os.ReadDir(p.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// Kernel threads and other users' processes have no readable exe
+		exe, err := os.Readlink(filepath.Join(p.Root, entry.Name(), "exe"))
+		if err != nil {
+			continue
+		}
+		procs = append(procs, Process{PID: pid, Executable: strings.TrimSuffix(exe, " (deleted)")})
+	}
+	return procs, nil
+}
+
+func (Proc) Signal(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+// Fake is an in memory process table. Used in tests
+type Fake struct {
+	mu        sync.Mutex
+	Processes []Process
+	Stubborn  map[int]bool             // processes ignoring SIGTERM
+	Signals   map[int][]syscall.Signal // signals sent to each process
+}
+
+func (f *Fake) List() ([]Process, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Process(nil), f.Processes...), nil
+}
+
+// Records the signal and removes the process unless it ignores SIGTERM
+func (f *Fake) Signal(pid int, sig syscall.Signal) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Signals == nil {
+		f.Signals = map[int][]syscall.Signal{}
+	}
+	f.Signals[pid] = append(f.Signals[pid], sig)
+	if sig == syscall.SIGTERM && f.Stubborn[pid] {
+		return nil
+	}
+	for i, proc := range f.Processes {
+		if proc.PID == pid {
+			f.Processes = slices.Delete(slices.Clone(f.Processes), i, i+1)
+			return nil
+		}
+	}
+	return syscall.ESRCH
+}
+
+// Running returns the processes whose executable is one of paths or lives
+// inside one of them, leaving out the current process
+func Running(src Source, paths []string) ([]Process, error) {
+	procs, err := src.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var running []Process
+	for _, proc := range procs {
+		if proc.PID == os.Getpid() {
+			continue
+		}
+		for _, path := range paths {
+			if proc.Executable == path || strings.HasPrefix(proc.Executable, path+string(os.PathSeparator)) {
+				running = append(running, proc)
+				break
+			}
+		}
+	}
+	return running, nil
+}
+
+// Quit asks the processes to terminate with SIGTERM and kills the ones
+// still running after timeout with SIGKILL.
+//
+// Returns the processes that could not be stopped
+func Quit(src Source, procs []Process, timeout time.Duration) []Process {
+	for _, proc := range procs {
+		src.Signal(proc.PID, syscall.SIGTERM)
+	}
+	remaining := waitExit(src, procs, timeout)
+
+	for _, proc := range remaining {
+		src.Signal(proc.PID, syscall.SIGKILL)
+	}
+	return waitExit(src, remaining, time.Second)
+}
+
+// Waits until none of the processes is running or the timeout
+// passes and returns the ones still running
+func waitExit(src Source, procs []Process, timeout time.Duration) []Process {
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := stillRunning(src, procs)
+		if len(remaining) == 0 || time.Now().After(deadline) {
+			return remaining
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Returns the processes that are still listed by the source
+func stillRunning(src Source, procs []Process) []Process {
+	current, err := src.List()
+	if err != nil {
+		return procs
+	}
+	alive := map[Process]bool{}
+	for _, proc := range current {
+		alive[proc] = true
+	}
+
+	var remaining []Process
+	for _, proc := range procs {
+		if alive[proc] {
+			remaining = append(remaining, proc)
+		}
+	}
+	return remaining
+}
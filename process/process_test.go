@@ -0,0 +1,63 @@
+package process
+
+import (
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestProc(t *testing.T) {
+	if _, err := os.Stat("/proc/self/exe"); err != nil {
+		t.Skip("no procfs on this platform")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	procs, err := Proc{Root: "/proc"}.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(procs, Process{PID: os.Getpid(), Executable: exe}) {
+		t.Errorf("Expected the test process %d running %s to be listed", os.Getpid(), exe)
+	}
+}
+
+func TestRunning(t *testing.T) {
+	fake := &Fake{Processes: []Process{
+		{PID: 10, Executable: "/Applications/Foo.app/Contents/MacOS/Foo"},
+		{PID: 11, Executable: "/Applications/Foo.app/Contents/Frameworks/Foo Helper.app/Contents/MacOS/Foo Helper"},
+		{PID: 12, Executable: "/Library/PrivilegedHelperTools/com.foo.helper"},
+		{PID: 13, Executable: "/Applications/Foo Beta.app/Contents/MacOS/Foo"},
+	}}
+
+	running, err := Running(fake, []string{"/Applications/Foo.app", "/Library/PrivilegedHelperTools/com.foo.helper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pids []int
+	for _, proc := range running {
+		pids = append(pids, proc.PID)
+	}
+	if !slices.Equal(pids, []int{10, 11, 12}) {
+		t.Errorf("Expected processes 10, 11 and 12, got %v", pids)
+	}
+}
+
+func TestQuit(t *testing.T) {
+	procs := []Process{{PID: 10, Executable: "/a"}, {PID: 11, Executable: "/b"}}
+	fake := &Fake{Processes: procs, Stubborn: map[int]bool{11: true}}
+
+	if remaining := Quit(fake, procs, 200*time.Millisecond); len(remaining) != 0 {
+		t.Errorf("Expected every process to be stopped, got %v", remaining)
+	}
+	if !slices.Equal(fake.Signals[10], []syscall.Signal{syscall.SIGTERM}) {
+		t.Errorf("Expected process 10 to quit on SIGTERM, got %v", fake.Signals[10])
+	}
+	if !slices.Equal(fake.Signals[11], []syscall.Signal{syscall.SIGTERM, syscall.SIGKILL}) {
+		t.Errorf("Expected process 11 to be killed after ignoring SIGTERM, got %v", fake.Signals[11])
+	}
+}
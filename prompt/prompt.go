@@ -0,0 +1,82 @@
+// Package prompt holds the small interactive helpers rmapp uses to ask the
+// user for confirmation or a choice on the command line.
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Input is where answers are read from. Replaced in tests
+var Input io.Reader = os.Stdin
+
+// Output is where questions and messages are written to. Set to stderr
+// when stdout carries a machine readable report
+var Output io.Writer = os.Stdout
+
+var (
+	reader    *bufio.Reader
+	readerSrc io.Reader
+)
+
+// Reads a single trimmed line of input, reusing the buffered reader between calls
+func readLine() (string, error) {
+	if reader == nil || readerSrc != Input {
+		reader = bufio.NewReader(Input)
+		readerSrc = Input
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Confirm asks a yes/no question and returns true only on an explicit yes.
+//
+// Any read error such as a closed stdin is treated as no
+func Confirm(question string) bool {
+	fmt.Fprintf(Output, "%s [y/N]: ", question)
+	answer, err := readLine()
+	if err != nil {
+		fmt.Fprintln(Output)
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// Choose prints the numbered choices and returns the index the user picked.
+//
+// Returns false if the user skipped with an empty answer or input was unavailable
+func Choose(question string, choices []string) (int, bool) {
+	if len(choices) == 0 {
+		return 0, false
+	}
+
+	fmt.Fprintln(Output, question)
+	for i, choice := range choices {
+		fmt.Fprintf(Output, "  %d) %s\n", i+1, choice)
+	}
+
+	for {
+		fmt.Fprintf(Output, "Select 1-%d (Enter to skip): ", len(choices))
+		answer, err := readLine()
+		if err != nil {
+			fmt.Fprintln(Output)
+			return 0, false
+		}
+		if answer == "" {
+			return 0, false
+		}
+		n, err := strconv.Atoi(answer)
+		if err == nil && n >= 1 && n <= len(choices) {
+			return n - 1, true
+		}
+		fmt.Fprintf(Output, "Invalid selection %q\n", answer)
+	}
+}
@@ -0,0 +1,117 @@
+package resolver
+
+/*
+Copies.go holds the logic for choosing between several installed
+copies of the same application bundle
+*/
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alewtschuk/pfmt"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/prompt"
+)
+
+// Asks which copy of the app to remove when more than one bundle shares the
+// bundle ID and returns the copies that must be kept.
+//
+// Returns nil when every copy is removed. Reports list every copy
+// without asking, and skipping the choice aborts the removal
+func chooseCopies(app string, copies []finder.Bundle, opts options.Options) []string {
+	if len(copies) < 2 {
+		return nil
+	}
+
+	if opts.Peek || opts.Size {
+		fmt.Fprintf(prompt.Output, "[rmapp] Found %d copies of %s:\n", len(copies), pfmt.ApplyColor(app, 2))
+		for _, bundle := range copies {
+			fmt.Fprintf(prompt.Output, "  • %s %s\n", pfmt.ApplyColor(bundle.Path, 3), bundleVersion(bundle))
+		}
+		return nil
+	}
+
+	var choices []string
+	for _, bundle := range copies {
+		choices = append(choices, fmt.Sprintf("%s %s", pfmt.ApplyColor(bundle.Path, 3), bundleVersion(bundle)))
+	}
+	choices = append(choices, "All copies and their associated files")
+
+	idx, ok := prompt.Choose(fmt.Sprintf("[rmapp] Found %d copies of %s. Which should be removed?", len(copies), pfmt.ApplyColor(app, 2)), choices)
+	if !ok {
+		fmt.Fprintln(prompt.Output, "[rmapp] No copy selected. Nothing was removed.")
+		os.Exit(0)
+	}
+	if idx == len(copies) {
+		return nil
+	}
+
+	var keep []string
+	for i, bundle := range copies {
+		if i != idx {
+			keep = append(keep, bundle.Path)
+		}
+	}
+	return keep
+}
+
+// Returns the copies other than the one at appPath, which are kept when a
+// path to that copy is passed as the target.
+//
+// Compares the files rather than paths as the target has its symlinks resolved
+func otherCopies(appPath string, copies []finder.Bundle) []string {
+	selected, err := os.Stat(appPath)
+	if err != nil {
+		return nil
+	}
+
+	var keep []string
+	for _, bundle := range copies {
+		if info, err := os.Stat(bundle.Path); err != nil || !os.SameFile(info, selected) {
+			keep = append(keep, bundle.Path)
+		}
+	}
+	if len(keep) == len(copies) {
+		return nil
+	}
+	return keep
+}
+
+// Narrows matches down to the selected copy.
+//
+// Associated files are shared by every copy with the same bundle ID,
+// so they are kept while another copy remains installed
+func onlySelectedCopy(matches []*finder.Match, keep []string) []*finder.Match {
+	var selected []*finder.Match
+	for _, match := range matches {
+		if isKept(match.Path, keep) {
+			continue
+		}
+		if match.Kind == finder.KindBundle {
+			selected = append(selected, match)
+		}
+	}
+	fmt.Fprintln(prompt.Output, "[rmapp] Other copies remain installed. Keeping their shared associated files.")
+	return selected
+}
+
+// Checks if path is or lives inside one of the kept bundles
+func isKept(path string, keep []string) bool {
+	for _, kept := range keep {
+		if path == kept || strings.HasPrefix(path, kept+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Formats the bundle version for display
+func bundleVersion(bundle finder.Bundle) string {
+	if bundle.Info.ShortVersion == "" {
+		return ""
+	}
+	return "(" + bundle.Info.ShortVersion + ")"
+}
@@ -0,0 +1,213 @@
+package resolver
+
+/*
+Identifiers.go holds the logic for deriving the extra identifiers an app may
+store data under from the helpers, services and extensions embedded in its bundle
+*/
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/codesign"
+	"github.com/alewtschuk/rmapp/desktop"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/index"
+	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/plist"
+)
+
+// Identifier prefixes of frameworks embedded by many unrelated apps.
+// Matching on them would attribute other apps' data to the target
+var sharedPrefixes = []string{"com.apple.", "org.sparkle-project."}
+
+// Locations of embedded bundles inside Contents and the source they are recorded as
+var embeddedDirs = []struct {
+	dir    string
+	ext    string
+	source string
+}{
+	{"Library/LoginItems", ".app", finder.SourceLoginItem},
+	{"XPCServices", ".xpc", finder.SourceXPCService},
+	{"PlugIns", ".appex", finder.SourceExtension},
+	{"Library/LaunchServices", "", finder.SourceHelper},
+}
+
+// Reads the code signature of a bundle's main executable. Replaced in tests
+var readSignature = codesign.ReadBundle
+
+// Collects the team ID and every extra identifier of the bundle
+// from its embedded bundles and code signature.
+//
+// Groups also declared by another installed bundle are marked shared.
+// The groups of the installed bundles are only read if the app declares any
+func bundleIdentifiers(appPath string, info plist.BundleInfo, installed func() groupInventory) (string, []finder.Identifier) {
+	identifiers := embeddedIdentifiers(appPath, info)
+	// Desktop entries embed nothing and carry no code signature
+	if desktop.IsEntryPath(appPath) {
+		return "", identifiers
+	}
+	teamID, groups := signatureIdentifiers(appPath, info)
+	if len(groups) > 0 {
+		groups = finder.MarkShared(groups, installed().others(appPath, info.Identifier))
+	}
+	for _, group := range groups {
+		identifiers = finder.AddIdentifier(identifiers, group)
+	}
+	return teamID, identifiers
+}
+
+// Enumerates the bundles embedded in the app and builds the set of identifiers
+// its data may be stored under besides the main bundle ID.
+//
+// Covers login items, XPC services, app extensions, SMPrivilegedExecutables
+// helpers and the CFBundleExecutable names of the app and its embedded bundles
+func embeddedIdentifiers(appPath string, info plist.BundleInfo) []finder.Identifier {
+	var ids []finder.Identifier
+	add := func(value, source string) {
+		if value == "" || isSharedIdentifier(value) {
+			return
+		}
+		// The main bundle ID already matches anything it prefixes
+		if info.Identifier != "" && strings.Contains(strings.ToLower(value), strings.ToLower(info.Identifier)) {
+			return
+		}
+		ids = finder.AddIdentifier(ids, finder.Identifier{Value: value, Source: source})
+	}
+
+	add(info.Executable, finder.SourceExecutable)
+	for _, helper := range info.PrivilegedExecutables {
+		add(helper, finder.SourceHelper)
+	}
+
+	for _, embedded := range embeddedDirs {
+		entries, err := os.ReadDir(filepath.Join(appPath, "Contents", embedded.dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+
+			// Privileged helper tools are plain executables named after their label
+			if embedded.ext == "" {
+				if !entry.IsDir() {
+					add(name, embedded.source)
+				}
+				continue
+			}
+
+			if !strings.HasSuffix(name, embedded.ext) {
+				continue
+			}
+			embeddedInfo, err := plist.ReadBundleInfo(filepath.Join(appPath, "Contents", embedded.dir, name))
+			if err != nil || isSharedIdentifier(embeddedInfo.Identifier) {
+				continue
+			}
+			add(embeddedInfo.Identifier, embedded.source)
+			add(embeddedInfo.Executable, finder.SourceExecutable)
+		}
+	}
+
+	return ids
+}
+
+// Checks if the identifier belongs to a framework shared across apps
+func isSharedIdentifier(value string) bool {
+	lower := strings.ToLower(value)
+	for _, prefix := range sharedPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reads the code signature of the app's main executable and returns its team ID
+// along with exact identifiers for its app and keychain groups
+func signatureIdentifiers(appPath string, info plist.BundleInfo) (string, []finder.Identifier) {
+	sig, err := readSignature(appPath, info.Executable)
+	if err != nil {
+		log.Printf("Could not read code signature of %s: %v", appPath, err)
+		return "", nil
+	}
+	return sig.TeamID, finder.GroupIdentifiers(sig.TeamID, sig.AppGroups, sig.KeychainGroups)
+}
+
+// groupInventory holds the app and keychain groups each installed bundle declares
+type groupInventory []bundleGroups
+
+// Groups declared by a single bundle
+type bundleGroups struct {
+	bundle finder.Bundle
+	groups []string
+}
+
+// Reads the groups of every installed bundle.
+//
+// Signatures are cached in the scan index until the executable changes,
+// so only apps installed or updated since the last run are read
+func readGroupInventory(installed []finder.Bundle, opts options.Options) groupInventory {
+	var ix *index.Index
+	if !opts.NoCache {
+		ix = index.Default()
+	}
+
+	var inventory groupInventory
+	for _, bundle := range installed {
+		if desktop.IsEntryPath(bundle.Path) {
+			continue
+		}
+		read := func() ([]string, error) {
+			sig, err := readSignature(bundle.Path, bundle.Info.Executable)
+			if err != nil {
+				return nil, err
+			}
+			var values []string
+			for _, id := range finder.GroupIdentifiers(sig.TeamID, sig.AppGroups, sig.KeychainGroups) {
+				values = append(values, id.Value)
+			}
+			return values, nil
+		}
+
+		var groups []string
+		var err error
+		if ix != nil {
+			groups, err = ix.FileValues(executablePath(bundle), read)
+		} else {
+			groups, err = read()
+		}
+		if err == nil && len(groups) > 0 {
+			inventory = append(inventory, bundleGroups{bundle: bundle, groups: groups})
+		}
+	}
+	return inventory
+}
+
+// Returns the groups of every bundle other than the app and its copies,
+// which vendors share across their apps
+func (inv groupInventory) others(appPath, bundleID string) []finder.Identifier {
+	var groups []finder.Identifier
+	for _, entry := range inv {
+		if entry.bundle.Path == appPath {
+			continue
+		}
+		if bundleID != "" && strings.EqualFold(entry.bundle.Info.Identifier, bundleID) {
+			continue
+		}
+		for _, group := range entry.groups {
+			groups = append(groups, finder.Identifier{Value: group})
+		}
+	}
+	return groups
+}
+
+// Returns the path of the bundle's main executable
+func executablePath(bundle finder.Bundle) string {
+	executable := bundle.Info.Executable
+	if executable == "" {
+		executable = strings.TrimSuffix(filepath.Base(bundle.Path), filepath.Ext(bundle.Path))
+	}
+	return filepath.Join(bundle.Path, "Contents", "MacOS", executable)
+}
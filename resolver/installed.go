@@ -0,0 +1,66 @@
+package resolver
+
+import (
+	"sync"
+
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/options"
+)
+
+// Finds the installed apps that likely own the path
+func FindOwners(path string, opts options.Options) []finder.Owner {
+	return finder.FindOwners(path, installedTargets(opts), opts)
+}
+
+// Finds bundle ID named data of apps that are no longer installed.
+//
+// Besides the installed bundle IDs, the identifiers of embedded helpers,
+// extensions and app groups are indexed so their data is not reported
+func FindOrphans(opts options.Options) []finder.Orphan {
+	var installed []string
+	for _, target := range installedTargets(opts) {
+		installed = append(installed, target.BundleID)
+		for _, id := range target.Identifiers {
+			installed = append(installed, id.Value)
+		}
+	}
+	return finder.FindOrphans(installed, opts)
+}
+
+// Attributes the associated files of every installed app in a single
+// walk over the search roots
+func Footprints(opts options.Options) []finder.Footprint {
+	return finder.Attribute(installedTargets(opts), opts)
+}
+
+// Resolves every discovered bundle into a target
+func installedTargets(opts options.Options) []finder.Target {
+	bundles := finder.DiscoverInstalled(opts)
+	groups := inventoryOnce(bundles, opts)
+
+	var targets []finder.Target
+	for _, bundle := range bundles {
+		targets = append(targets, bundleTarget(bundle, groups))
+	}
+	return targets
+}
+
+// Returns a function reading the group inventory of the bundles on first use
+func inventoryOnce(bundles []finder.Bundle, opts options.Options) func() groupInventory {
+	return sync.OnceValue(func() groupInventory {
+		return readGroupInventory(bundles, opts)
+	})
+}
+
+// Resolves the bundle into its bundle ID and the identifiers
+// of its embedded helpers, extensions and code signature
+func bundleTarget(bundle finder.Bundle, installed func() groupInventory) finder.Target {
+	teamID, identifiers := bundleIdentifiers(bundle.Path, bundle.Info, installed)
+	return finder.Target{
+		AppName:     bundle.Name(),
+		BundleID:    bundle.Info.Identifier,
+		BundlePath:  bundle.Path,
+		TeamID:      teamID,
+		Identifiers: identifiers,
+	}
+}
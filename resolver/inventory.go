@@ -0,0 +1,58 @@
+package resolver
+
+/*
+Inventory.go holds the logic for listing the installed applications
+with their metadata and sizes
+*/
+
+import (
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/options"
+)
+
+// App holds an installed application's metadata and sizes
+type App struct {
+	Name      string `json:"name"`                // bundle name without .app
+	BundleID  string `json:"bundle_id"`           // CFBundleIdentifier
+	Version   string `json:"version"`             // CFBundleShortVersionString, falling back to CFBundleVersion
+	Path      string `json:"path"`                // full path to the .app bundle
+	Size      int64  `json:"size"`                // size of the bundle in bytes
+	Footprint int64  `json:"footprint,omitempty"` // size of the bundle and its associated files in bytes
+}
+
+// Lists every discovered app bundle with its metadata and bundle size.
+//
+// When footprint is set, the associated files of every app are attributed
+// in a single walk over the search roots as well
+func ListApps(footprint bool, opts options.Options) []App {
+	bundles := finder.DiscoverInstalled(opts)
+
+	var footprints []finder.Footprint
+	if footprint {
+		groups := inventoryOnce(bundles, opts)
+		targets := make([]finder.Target, len(bundles))
+		for i, bundle := range bundles {
+			targets[i] = bundleTarget(bundle, groups)
+		}
+		footprints = finder.Attribute(targets, opts)
+	}
+
+	var apps []App
+	for i, bundle := range bundles {
+		app := App{
+			Name:     bundle.Name(),
+			BundleID: bundle.Info.Identifier,
+			Version:  bundle.Info.ShortVersion,
+			Path:     bundle.Path,
+			Size:     finder.PathSize(bundle.Path, opts),
+		}
+		if app.Version == "" {
+			app.Version = bundle.Info.Version
+		}
+		if footprint {
+			app.Footprint = footprints[i].Size(opts.Logical)
+		}
+		apps = append(apps, app)
+	}
+	return apps
+}
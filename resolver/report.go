@@ -0,0 +1,41 @@
+package resolver
+
+/*
+Report.go holds the logic for writing the resolved app and the outcome of
+its removal as a machine readable report
+*/
+
+import (
+	"io"
+	"strings"
+
+	"github.com/alewtschuk/rmapp/output"
+)
+
+// Writes the matches of the resolved app as a report in the format set in
+// the options, removing them first unless only peeking or sizing.
+//
+// Only the report is written to out. Messages and prompts go to prompt.Output
+func (r *Resolver) WriteReport(out io.Writer) error {
+	kind := output.KindRemove
+	switch {
+	case r.Options.Size:
+		kind = output.KindSize
+	case r.Options.Peek:
+		kind = output.KindPeek
+	}
+
+	app := strings.TrimSuffix(r.AppName, ".app")
+	w := output.NewWriter(out, r.Options.Output, kind, app, r.BundleID)
+	w.Matches(r.Finder.Matches, r.Options.Logical)
+
+	var err error
+	if kind == output.KindRemove {
+		r.Deleter.OnResult(w.Result)
+		err = r.Deleter.Delete()
+	}
+	if writeErr := w.Close(r.Deleter.Run(), err); err == nil {
+		err = writeErr
+	}
+	return err
+}
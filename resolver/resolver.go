@@ -11,33 +11,92 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/alewtschuk/pfmt"
 	"github.com/alewtschuk/rmapp/deleter"
+	"github.com/alewtschuk/rmapp/desktop"
 	"github.com/alewtschuk/rmapp/finder"
 	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/plist"
+	"github.com/alewtschuk/rmapp/prompt"
 )
 
 // Resolver holds all the information reagarding the application's info
 type Resolver struct {
-	AppName       string          // full .app name to be deleted
-	MdlsReturnStr string          // full return string of the mlds command call
-	BundleID      string          // app's bundle ID
-	Finder        finder.Finder   // finder to look for files using app info
-	Options       options.Options // resolver options
-	Deleter       deleter.Deleter // deleter struct for handling file removal
-	Reported      bool            // resolved if peek or size is true
-	BundleOnly    bool            // holds if only the bundle will be removed
+	AppName       string              // full .app name to be deleted
+	AppPath       string              // full path to the .app bundle
+	Info          plist.BundleInfo    // metadata read from the bundle's Info.plist
+	MdlsReturnStr string              // full return string of the mlds command call, empty if Info.plist was used
+	BundleID      string              // app's bundle ID
+	TeamID        string              // developer team ID from the app's code signature
+	Identifiers   []finder.Identifier // identifiers of embedded helpers, services and extensions
+	Finder        finder.Finder       // finder to look for files using app info
+	Options       options.Options     // resolver options
+	Deleter       deleter.Deleter     // deleter struct for handling file removal
+	Reported      bool                // resolved if peek or size is true
+	BundleOnly    bool                // holds if only the bundle will be removed
+	Leftovers     bool                // true if the bundle is gone and only leftover files are searched
 }
 
-// Creates resolver struct and populates fields
-func NewResolver(app string, opts options.Options) *Resolver {
+// Creates resolver struct and populates fields.
+//
+// The target may be an app name, a bundle ID or a path to or into an app bundle
+func NewResolver(target string, opts options.Options) *Resolver {
+	resolved, err := resolveTarget(target, &opts)
+	if err != nil {
+		fmt.Fprintln(prompt.Output, pfmt.ApplyColor("[rmapp] Error: "+err.Error(), 9))
+		os.Exit(1)
+	}
+	app, appPath := resolved.app, resolved.appPath
 	appName := getDotApp(app)
-	mdlsReturnStr := getMdlsIdentifier(appName)
+	info, mdlsReturnStr, bundleID := readBundleID(appPath, opts)
+
+	// An explicitly passed bundle ID always takes precedence
+	if opts.BundleID != "" {
+		bundleID = opts.BundleID
+	}
+
+	// Without a bundle on disk only the app's leftovers can be cleaned
+	leftovers := !bundleExists(appPath)
+	if leftovers {
+		fmt.Fprintf(prompt.Output, "[rmapp] App %s not found. Searching for leftover files...\n", pfmt.ApplyColor(app, 2))
+		if opts.BundleOnly {
+			fmt.Fprintln(prompt.Output, "[rmapp] No bundle to remove. Run again without '--bundle' to remove leftover files")
+			os.Exit(1)
+		}
+		if bundleID == "" {
+			bundleID = chooseBundleID(app, opts)
+		}
+		appPath = ""
+	}
+
+	// Let the user pick which copy to remove when several share the bundle ID
+	var keepCopies []string
+	var teamID string
+	var identifiers []finder.Identifier
+	if !leftovers {
+		// A path names the copy to remove, so only names and bundle IDs ask
+		copies := finder.BundlesWithID(resolved.installedBundles(opts), bundleID)
+		if resolved.explicit {
+			keepCopies = otherCopies(appPath, copies)
+		} else {
+			keepCopies = chooseCopies(app, copies, opts)
+		}
+		teamID, identifiers = bundleIdentifiers(appPath, info, resolved.installedGroups(opts))
+	}
+
 	if opts.Verbosity {
 		log.Println("\nApplication to delete: ", pfmt.ApplyColor(app, 2))
-		log.Print("Resolved Bundle ID: ", pfmt.ApplyColor(getBundleID(mdlsReturnStr), 2), "\n\n")
+		log.Print("Resolved Bundle ID: ", pfmt.ApplyColor(bundleID, 2), "\n")
+		if teamID != "" {
+			log.Print("Resolved Team ID: ", pfmt.ApplyColor(teamID, 2), "\n")
+		}
+		for _, id := range identifiers {
+			log.Printf("Derived %s: %s", id.Source, pfmt.ApplyColor(id.Value, 2))
+		}
+		log.Println()
 	}
 
 	// Sets if a report and exit is needed
@@ -46,52 +105,166 @@ func NewResolver(app string, opts options.Options) *Resolver {
 		isReported = true
 	}
 
-	finder := finder.NewFinder(app, getBundleID((mdlsReturnStr)), opts) // uses app name over .app to ensure propper name based searching
+	// Uses app name over .app to ensure propper name based searching
+	finder := finder.NewTargetFinder(finder.Target{AppName: app, BundleID: bundleID, BundlePath: appPath, TeamID: teamID, Identifiers: identifiers}, opts)
+	if len(keepCopies) > 0 {
+		finder.Matches = onlySelectedCopy(finder.Matches, keepCopies)
+	}
+
+	if leftovers && len(finder.Matches) == 0 && !isReported {
+		fmt.Fprintf(prompt.Output, "[rmapp] No leftover files found for %s.\n", pfmt.ApplyColor(app, 2))
+		os.Exit(1)
+	}
 
 	resolver := &Resolver{
 		AppName:       appName,
+		AppPath:       appPath,
+		Info:          info,
 		MdlsReturnStr: mdlsReturnStr,
-		BundleID:      getBundleID(mdlsReturnStr),
+		BundleID:      bundleID,
+		TeamID:        teamID,
+		Identifiers:   identifiers,
 		Finder:        finder,
 		Options:       opts,
-		Deleter:       deleter.NewDeleter(finder.MatchedPaths, opts),
+		Deleter:       deleter.NewAppDeleter(appName, bundleID, finder.Matches, opts),
 		Reported:      isReported,
 		BundleOnly:    opts.BundleOnly,
+		Leftovers:     leftovers,
 	}
 
 	return resolver
 }
 
-// Calls mdls to retrieve the bundle identifier
-// and converts the bundle identifier to a string
-func getMdlsIdentifier(appName string) string {
-	if !strings.HasPrefix(appName, "/") {
-		appName = fmt.Sprintf("/Applications/%s", appName)
+// ResolveTarget resolves the target into everything the finder searches for
+// without scanning, prompting or exiting.
+//
+// Used by commands that inspect matching rather than remove an app. BundlePath
+// is empty and only the name and any explicit bundle ID are used for leftovers
+func ResolveTarget(target string, opts options.Options) (finder.Target, error) {
+	r, err := resolveTarget(target, &opts)
+	if err != nil {
+		return finder.Target{}, err
+	}
+	app, appPath := r.app, r.appPath
+
+	info, _, bundleID := readBundleID(appPath, opts)
+	if opts.BundleID != "" {
+		bundleID = opts.BundleID
 	}
 
-	out, err := exec.Command("mdls", appName, "-name", "kMDItemCFBundleIdentifier").Output()
+	resolved := finder.Target{AppName: app, BundleID: bundleID}
+	if bundleExists(appPath) {
+		resolved.BundlePath = appPath
+		resolved.TeamID, resolved.Identifiers = bundleIdentifiers(appPath, info, r.installedGroups(opts))
+	}
+	return resolved, nil
+}
+
+// Reads the bundle ID from the bundle's Info.plist or desktop entry.
+//
+// Prefers reading the Info.plist directly and only falls back to mdls when
+// the plist is missing or carries no identifier. Spotlight only indexes the
+// running system so there is no fallback under --root
+func readBundleID(appPath string, opts options.Options) (plist.BundleInfo, string, string) {
+	var mdlsReturnStr string
+	info, err := finder.ReadBundleInfo(appPath)
+	bundleID := info.Identifier
+	if (err != nil || bundleID == "") && !desktop.IsEntryPath(appPath) && opts.Root == "" {
+		log.Printf("Could not read Info.plist for %s, falling back to mdls: %v", appPath, err)
+		if mdlsReturnStr, err = getMdlsIdentifier(appPath); err == nil {
+			bundleID, _ = getBundleID(mdlsReturnStr)
+		}
+	}
+	return info, mdlsReturnStr, bundleID
+}
+
+// Infers possible bundle IDs from the app's leftover files and
+// lets the user confirm one.
+//
+// Returns "" if none were found or the user skipped, which
+// falls back to searching by name only
+func chooseBundleID(app string, opts options.Options) string {
+	candidates := finder.InferBundleIDs(app, opts)
+	if len(candidates) == 0 {
+		fmt.Fprintln(prompt.Output, "[rmapp] Could not infer a bundle ID. Searching by name only...")
+		return ""
+	}
+
+	var choices []string
+	for _, candidate := range candidates {
+		choices = append(choices, fmt.Sprintf("%s (%d files)", pfmt.ApplyColor(candidate.BundleID, 2), len(candidate.Evidence)))
+	}
+
+	idx, ok := prompt.Choose(fmt.Sprintf("[rmapp] Possible bundle IDs for %s:", pfmt.ApplyColor(app, 2)), choices)
+	if !ok {
+		fmt.Fprintln(prompt.Output, "[rmapp] No bundle ID selected. Searching by name only...")
+		return ""
+	}
+
+	for _, path := range candidates[idx].Evidence {
+		log.Printf("Inferred %s from: %s", pfmt.ApplyColor(candidates[idx].BundleID, 2), pfmt.ApplyColor(path, 3))
+	}
+	return candidates[idx].BundleID
+}
+
+// Checks if the .app bundle or desktop entry exists on disk
+func bundleExists(appPath string) bool {
+	info, err := os.Stat(appPath)
+	if err == nil && desktop.IsEntryPath(appPath) {
+		return info.Mode().IsRegular()
+	}
+	return err == nil && info.IsDir()
+}
+
+// Returns the full path of the .app bundle.
+//
+// Absolute paths are returned as is, otherwise /Applications and
+// ~/Applications of the searched system are checked in order before
+// discovering bundles in nested folders, defaulting to /Applications
+func getAppPath(appName string, r *resolution, opts options.Options) string {
+	if strings.HasPrefix(appName, "/") {
+		return appName
+	}
+
+	candidates := []string{
+		filepath.Join(finder.Rebase("/Applications", opts), appName),
+		filepath.Join(finder.HomeDir(opts), "Applications", appName),
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	if found := finder.BundlesNamed(r.installedBundles(opts), appName); len(found) > 0 {
+		return found[0].Path
+	}
+	return candidates[0]
+}
+
+// Calls mdls to retrieve the bundle identifier
+// and converts the bundle identifier to a string
+func getMdlsIdentifier(appPath string) (string, error) {
+	out, err := exec.Command("mdls", appPath, "-name", "kMDItemCFBundleIdentifier").Output()
 	if err != nil {
-		appName = strings.TrimSuffix(strings.TrimPrefix(appName, "/Applications/"), ".app")
-		fmt.Printf("[rmapp] App %s not found.\n", pfmt.ApplyColor(appName, 2))
-		os.Exit(1)
+		return "", err
 	}
 	// Set full mlds output to string
 	mdlsReturnStr := string(out)
 
-	return mdlsReturnStr
+	return mdlsReturnStr, nil
 }
 
 // Takes mlds returned kMDItemCFBundleIdentifier
 // string and extracts the bundle id
-func getBundleID(mdlsReturnStr string) string {
+func getBundleID(mdlsReturnStr string) (string, error) {
 	bundleID, err := extractQuotedSubstring(mdlsReturnStr)
 	if err != nil {
-
-		fmt.Println(pfmt.ApplyColor("[rmapp] Error: BundleId is empty", 9))
-		os.Exit(1)
+		log.Println(pfmt.ApplyColor("[rmapp] Error: BundleId is empty", 9))
+		return "", err
 	}
 
-	return bundleID
+	return bundleID, nil
 }
 
 // Extracts substring between " delimiter
@@ -99,7 +272,7 @@ func getBundleID(mdlsReturnStr string) string {
 // For use to trim kMDItemCFBundleIdentifier string
 func extractQuotedSubstring(str string) (string, error) {
 	strs := strings.Split(str, "\"")
-	if len(strs) >= 2 {
+	if len(strs) >= 3 { // requires both an opening and closing quote
 		return strs[1], nil
 	}
 
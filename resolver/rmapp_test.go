@@ -1,15 +1,28 @@
 package resolver
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/alewtschuk/rmapp/codesign"
 	"github.com/alewtschuk/rmapp/deleter"
 	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/launchd"
 	"github.com/alewtschuk/rmapp/options"
+	"github.com/alewtschuk/rmapp/output"
+	"github.com/alewtschuk/rmapp/plist"
+	"github.com/alewtschuk/rmapp/process"
+	"github.com/alewtschuk/rmapp/prompt"
+	"github.com/alewtschuk/rmapp/trash"
 )
 
 // --- Test Helpers ---
@@ -148,12 +161,22 @@ func TestDeleterModes(t *testing.T) {
 		{"UnsafeMode (Delete)", true},
 	}
 
+	useJournalDir(t)
+	manifests := useManifestDir(t)
+	bin := &trash.Memory{}
+	previous := deleter.Trash
+	deleter.Trash = bin
+	t.Cleanup(func() { deleter.Trash = previous })
+
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			// Note: The SafeMode test will move temporary files to the system's Trash.
 			filesToDelete := makeTestFiles(t, 3, "test-deleter")
 			opts := options.Options{Mode: tc.isUnsafe}
-			d := deleter.NewDeleter(filesToDelete, opts)
+			var matches []*finder.Match
+			for _, path := range filesToDelete {
+				matches = append(matches, finder.NewMatch(path))
+			}
+			d := deleter.NewDeleter(matches, opts)
 
 			if err := d.Delete(); err != nil {
 				t.Fatalf("Delete failed: %v", err)
@@ -165,10 +188,231 @@ func TestDeleterModes(t *testing.T) {
 					t.Errorf("Expected file %s to be gone from original location, but it still exists", path)
 				}
 			}
+
+			// Only trashed files are recorded for restore
+			trashed := 0
+			for _, original := range bin.Originals {
+				if slices.Contains(filesToDelete, original) {
+					trashed++
+				}
+			}
+			if !tc.isUnsafe && trashed != len(filesToDelete) {
+				t.Errorf("Expected all files in the trash, got %d of %d", trashed, len(filesToDelete))
+			}
+			if tc.isUnsafe && trashed != 0 {
+				t.Errorf("Expected force mode to bypass the trash, got %d files in it", trashed)
+			}
+			if entries, _ := os.ReadDir(manifests); len(entries) != 1 {
+				t.Errorf("Expected one recorded trashing run, got %d", len(entries))
+			}
 		})
 	}
 }
 
+// Keeps run manifests of the test in a temporary directory
+func useManifestDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous := deleter.ManifestDir
+	deleter.ManifestDir = dir
+	t.Cleanup(func() { deleter.ManifestDir = previous })
+	return dir
+}
+
+// Keeps removal journals of the test in a temporary directory
+func useJournalDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous := deleter.JournalDir
+	deleter.JournalDir = dir
+	t.Cleanup(func() { deleter.JournalDir = previous })
+	return dir
+}
+
+// Checks that only the given paths exist
+func assertExist(t *testing.T, exist bool, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		if _, err := os.Lstat(path); (err == nil) != exist {
+			t.Errorf("Expected %s to exist: %v, got error %v", path, exist, err)
+		}
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	journals := useJournalDir(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "com.gemini.test.plist")
+	folder := filepath.Join(dir, "com.gemini.test")
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(folder, "data"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertNoJournals := func() {
+		t.Helper()
+		if entries, _ := os.ReadDir(journals); len(entries) != 0 {
+			t.Errorf("Expected no journals left, found %d", len(entries))
+		}
+	}
+	stage := func() *deleter.Transaction {
+		t.Helper()
+		tx, err := deleter.NewTransaction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{file, folder} {
+			if err := tx.Stage(path); err != nil {
+				t.Fatalf("Stage(%s) failed: %v", path, err)
+			}
+		}
+		assertExist(t, false, file, folder)
+		return tx
+	}
+
+	t.Run("Rollback", func(t *testing.T) {
+		if err := stage().Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		assertExist(t, true, file, filepath.Join(folder, "data"))
+		assertNoJournals()
+	})
+
+	t.Run("Recover", func(t *testing.T) {
+		stage() // interrupted before committing
+		deleter.Recover()
+		assertExist(t, true, file, filepath.Join(folder, "data"))
+		assertNoJournals()
+	})
+
+	t.Run("RecoverReusedPID", func(t *testing.T) {
+		previous := deleter.ProcessStart
+		deleter.ProcessStart = func(int) string { return "Fri Oct 16 09:00:00 2026" }
+		t.Cleanup(func() { deleter.ProcessStart = previous })
+
+		// The parent process is alive, but only the journal started at its start time belongs to it
+		staged := filepath.Join(t.TempDir(), "0-com.gemini.test.plist")
+		if err := os.Rename(file, staged); err != nil {
+			t.Fatal(err)
+		}
+		for run, started := range map[string]string{"alive": "Fri Oct 16 09:00:00 2026", "stale": "Thu Oct 15 08:00:00 2026"} {
+			journal := deleter.Journal{Run: run, PID: os.Getppid(), Started: started, State: deleter.StateStaging}
+			if run == "stale" {
+				journal.Moves = []deleter.Move{{Original: file, Staged: staged}}
+			}
+			data, err := json.Marshal(journal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(journals, run+".json"), data, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		deleter.Recover()
+		assertExist(t, true, file, filepath.Join(journals, "alive.json"))
+		assertExist(t, false, filepath.Join(journals, "stale.json"))
+		os.Remove(filepath.Join(journals, "alive.json"))
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		tx := stage()
+		var staged []string
+		for _, move := range tx.Moves() {
+			staged = append(staged, move.Staged)
+		}
+		if err := tx.Commit(nil); err != nil {
+			t.Fatal(err)
+		}
+		assertExist(t, false, append(staged, file, folder)...)
+		assertNoJournals()
+	})
+}
+
+func TestRestore(t *testing.T) {
+	useManifestDir(t)
+	bin := &trash.Memory{}
+
+	library := t.TempDir()
+	restorable := filepath.Join(library, "Caches", "com.gemini.test")
+	occupied := filepath.Join(library, "Preferences", "com.gemini.test.plist")
+	emptied := filepath.Join(library, "Logs", "MyTestApp")
+	for _, path := range []string{filepath.Join(restorable, "data"), occupied, emptied} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest := deleter.NewManifest("MyTestApp.app", "com.gemini.test", bin.Name())
+	for _, path := range []string{restorable, occupied, emptied} {
+		trashed, err := bin.MoveToTrash(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest.Add(path, trashed)
+	}
+	assertExist(t, false, restorable, occupied, emptied)
+	if err := manifest.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reoccupy one location and empty another item from the trash
+	if err := os.WriteFile(occupied, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := bin.Restore(manifest.Items[2].Trashed, filepath.Join(t.TempDir(), "elsewhere")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"", manifest.Run, "MyTestApp", "mytestapp.app", "com.gemini.test"} {
+		if found, err := deleter.FindManifest(query); err != nil || found.Run != manifest.Run {
+			t.Errorf("FindManifest(%q) = %q, %v", query, found.Run, err)
+		}
+	}
+	if _, err := deleter.FindManifest("Slack"); !errors.Is(err, deleter.ErrNoRun) {
+		t.Errorf("Expected ErrNoRun for an unknown app, got %v", err)
+	}
+
+	found, _ := deleter.FindManifest("MyTestApp")
+	results := found.Restore(bin)
+	expected := []error{nil, deleter.ErrConflict, deleter.ErrMissing}
+	for i, result := range results {
+		if !errors.Is(result.Err, expected[i]) {
+			t.Errorf("Restoring %s returned %v, want %v", result.Item.Original, result.Err, expected[i])
+		}
+	}
+	assertExist(t, true, filepath.Join(restorable, "data"))
+	if err := found.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The conflict stays recorded until its location is freed
+	if err := os.Remove(occupied); err != nil {
+		t.Fatal(err)
+	}
+	found, _ = deleter.FindManifest(manifest.Run)
+	if len(found.Items) != 1 {
+		t.Fatalf("Expected only the conflict to remain, got %+v", found.Items)
+	}
+	if results := found.Restore(bin); results[0].Err != nil {
+		t.Fatalf("Restoring freed location failed: %v", results[0].Err)
+	}
+	if err := found.Save(); err != nil {
+		t.Fatal(err)
+	}
+	assertExist(t, true, occupied)
+	if manifests, _ := deleter.Manifests(); len(manifests) != 0 {
+		t.Errorf("Expected the fully restored run to be removed, got %d runs", len(manifests))
+	}
+}
+
 // TestFinder_FindsHomeDirFiles tests the finder's ability to discover files in a controlled environment.
 func TestFinder_FindsHomeDirFiles(t *testing.T) {
 	// --- Test Setup ---
@@ -184,12 +428,12 @@ func TestFinder_FindsHomeDirFiles(t *testing.T) {
 
 	// --- Test Execution ---
 	// We test the finder directly to avoid calling the real getBundleID, which is not easily mockable.
-	opts := options.Options{}
+	opts := options.Options{Platform: finder.DARWIN}
 	finder := finder.NewFinder(appName, bundleID, opts)
 
 	// --- Assertions ---
 	// The main check: did we find all the files we created in our fake home directory?
-	assertSlicesEqual(t, expectedPaths, finder.MatchedPaths)
+	assertSlicesEqual(t, expectedPaths, finder.Paths())
 }
 
 func TestFinder_SizeFlag(t *testing.T) {
@@ -198,10 +442,10 @@ func TestFinder_SizeFlag(t *testing.T) {
 	fakeHome, expectedPaths := setupTestFileSystem(t, appName, bundleID)
 	t.Setenv("HOME", fakeHome)
 
-	opts := options.Options{Size: true}
+	opts := options.Options{Platform: finder.DARWIN, Size: true}
 	finder := finder.NewFinder(appName, bundleID, opts)
 
-	assertSlicesEqual(t, expectedPaths, finder.MatchedPaths)
+	assertSlicesEqual(t, expectedPaths, finder.Paths())
 	if !finder.Reported {
 		t.Errorf("Expected finder.Reported to be true with --size flag, but got false")
 	}
@@ -222,8 +466,1242 @@ func TestFinder_BundleOnlyFlag(t *testing.T) {
 	// With BundleOnly, we only expect to find the .app bundle.
 	expectedPaths := []string{appPath}
 
-	opts := options.Options{BundleOnly: true}
+	opts := options.Options{Platform: finder.DARWIN, BundleOnly: true}
 	finder := finder.NewFinder(appName, bundleID, opts)
 
-	assertSlicesEqual(t, expectedPaths, finder.MatchedPaths)
+	assertSlicesEqual(t, expectedPaths, finder.Paths())
+}
+
+func TestInferBundleIDs(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	files := []string{
+		filepath.Join(fakeHome, "Library", "Preferences", "com.tinyspeck.slackmacgap.plist"),
+		filepath.Join(fakeHome, "Library", "Saved Application State", "com.tinyspeck.slackmacgap.savedState", "data.data"),
+		filepath.Join(fakeHome, "Library", "Preferences", "com.other.vendor.plist"),
+		filepath.Join(fakeHome, "Library", "Caches", "com.apple.slack"),
+	}
+	for _, path := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	candidates := finder.InferBundleIDs("Slack", options.Options{Platform: finder.DARWIN})
+	if len(candidates) != 1 {
+		t.Fatalf("Expected 1 candidate, got %d: %v", len(candidates), candidates)
+	}
+	if candidates[0].BundleID != "com.tinyspeck.slackmacgap" {
+		t.Errorf("Expected com.tinyspeck.slackmacgap, got %s", candidates[0].BundleID)
+	}
+	if len(candidates[0].Evidence) != 2 {
+		t.Errorf("Expected 2 evidence paths, got %v", candidates[0].Evidence)
+	}
+}
+
+func TestOwningBundle(t *testing.T) {
+	root := t.TempDir()
+	appPath := filepath.Join(root, "Applications", "Visual Studio Code.app")
+	binary := filepath.Join(appPath, "Contents", "Resources", "app", "bin", "code")
+	helper := filepath.Join(appPath, "Contents", "Frameworks", "Code Helper.app", "Contents", "MacOS", "Code Helper")
+	for _, path := range []string{binary, helper} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(root, "bin", "code")
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(binary, link); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resolve symlinks in the expected path as the temp dir may itself be a symlink
+	want, err := filepath.EvalSymlinks(appPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{appPath, binary, helper, link} {
+		got, err := owningBundle(target)
+		if err != nil {
+			t.Fatalf("owningBundle(%q) failed: %v", target, err)
+		}
+		if got != want {
+			t.Errorf("owningBundle(%q) = %q, want %q", target, got, want)
+		}
+	}
+
+	if _, err := owningBundle(filepath.Join(root, "bin")); err == nil {
+		t.Error("Expected error for path outside of a bundle")
+	}
+
+	gone := filepath.Join(root, "Applications", "Gone.app")
+	if got, err := owningBundle(gone); err != nil || got != gone {
+		t.Errorf("owningBundle(%q) = %q, %v; want the path back for leftovers", gone, got, err)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+	for path, want := range map[string]string{
+		"~":                     "/home/test",
+		"~/Applications/X.app":  "/home/test/Applications/X.app",
+		"/Applications/~X.app":  "/Applications/~X.app",
+		"Applications/X.app/~/": "Applications/X.app/~/",
+	} {
+		if got, err := expandHome(path); err != nil || got != want {
+			t.Errorf("expandHome(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+
+	if current, err := user.Current(); err == nil {
+		want := filepath.Join(current.HomeDir, "Applications", "X.app")
+		if got, err := expandHome("~" + current.Username + "/Applications/X.app"); err != nil || got != want {
+			t.Errorf("expandHome(~%s) = %q, %v; want %q", current.Username, got, err, want)
+		}
+	}
+	if got, err := expandHome("~rmapp-no-such-user/Applications/X.app"); err == nil {
+		t.Errorf("Expected an unknown user to be rejected, got %q", got)
+	}
+}
+
+func TestDiscoverBundles(t *testing.T) {
+	root := t.TempDir()
+	bundles := []string{
+		filepath.Join(root, "Slack.app"),
+		filepath.Join(root, "Utilities", "Terminal.app"),
+		filepath.Join(root, "Adobe Photoshop 2025", "Adobe Photoshop 2025.app"),
+		filepath.Join(root, "Chrome Apps.localized", "Gmail.app"),
+	}
+	ignored := []string{
+		filepath.Join(root, "Slack.app", "Contents", "Frameworks", "Slack Helper.app"),
+		filepath.Join(root, "a", "b", "c", "TooDeep.app"),
+	}
+	for _, path := range append(bundles, ignored...) {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var found []string
+	for _, bundle := range finder.DiscoverBundles([]string{root}, finder.DISCOVERY_DEPTH) {
+		found = append(found, bundle.Path)
+	}
+	assertSlicesEqual(t, bundles, found)
+
+	found = nil
+	for _, bundle := range finder.DiscoverBundles([]string{root}, 1) {
+		found = append(found, bundle.Path)
+	}
+	assertSlicesEqual(t, []string{filepath.Join(root, "Slack.app")}, found)
+}
+
+// writeInfoPlist writes a minimal XML Info.plist into the bundle at bundlePath.
+func writeInfoPlist(t *testing.T, bundlePath, bundleID, executable string) {
+	t.Helper()
+	contents := filepath.Join(bundlePath, "Contents")
+	if err := os.MkdirAll(contents, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>CFBundleIdentifier</key><string>%s</string>
+<key>CFBundleExecutable</key><string>%s</string>
+</dict></plist>`, bundleID, executable)
+	if err := os.WriteFile(filepath.Join(contents, "Info.plist"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTargetPath(t *testing.T) {
+	for target, want := range map[string]bool{
+		".":                   true,
+		"..":                  true,
+		"../Foo.app":          true,
+		"/Applications/X.app": true,
+		"~":                   true,
+		"Slack":               false,
+		"com.tinyspeck.slack": false,
+	} {
+		if got := isPathTarget(target); got != want {
+			t.Errorf("isPathTarget(%q) = %v, want %v", target, got, want)
+		}
+	}
+
+	root := t.TempDir()
+	opts := options.Options{Root: root, Home: "/Users/test"}
+	for target, want := range map[string]string{
+		"/Applications/X.app":                     filepath.Join(root, "Applications", "X.app"),
+		filepath.Join(root, "Applications/X.app"): filepath.Join(root, "Applications", "X.app"),
+		"~/Applications/X.app":                    filepath.Join(root, "Users", "test", "Applications", "X.app"),
+		"Applications/X.app":                      "Applications/X.app",
+	} {
+		if got, err := targetPath(target, opts); err != nil || got != want {
+			t.Errorf("targetPath(%q) = %q, %v; want %q", target, got, err, want)
+		}
+	}
+	if got, err := targetPath("~other/Applications/X.app", opts); err == nil {
+		t.Errorf("Expected ~user to be rejected under --root, got %q", got)
+	}
+}
+
+func TestExplicitCopy(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	selected := filepath.Join(fakeHome, "Applications", "Beta", "Slack.app")
+	other := filepath.Join(fakeHome, "Applications", "Slack.app")
+	writeInfoPlist(t, selected, "com.tinyspeck.slackmacgap", "Slack")
+	writeInfoPlist(t, other, "com.tinyspeck.slackmacgap", "Slack")
+
+	// A prompt would read EOF and abort, so reaching the assertions means none was shown
+	prompt.Input = strings.NewReader("")
+	t.Cleanup(func() { prompt.Input = os.Stdin })
+
+	instance := NewResolver(selected, options.Options{Platform: finder.DARWIN, NoCache: true})
+	want, err := filepath.EvalSymlinks(selected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSlicesEqual(t, []string{want}, instance.Finder.Paths())
+}
+
+func TestEmbeddedIdentifiers(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	appPath := filepath.Join(fakeHome, "Applications", "Docker.app")
+	writeInfoPlist(t, appPath, "com.docker.docker", "Docker")
+	writeInfoPlist(t, filepath.Join(appPath, "Contents", "Library", "LoginItems", "DockerHelper.app"), "com.electron.dockerdesktop", "DockerHelper")
+	writeInfoPlist(t, filepath.Join(appPath, "Contents", "XPCServices", "Updater.xpc"), "com.docker.docker.updater", "Updater")
+	writeInfoPlist(t, filepath.Join(appPath, "Contents", "XPCServices", "Sparkle.xpc"), "org.sparkle-project.Downloader", "Downloader")
+	helper := filepath.Join(appPath, "Contents", "Library", "LaunchServices", "com.docker.vmnetd")
+	if err := os.MkdirAll(filepath.Dir(helper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(helper, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := plist.ReadBundleInfo(appPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for _, id := range embeddedIdentifiers(appPath, info) {
+		values = append(values, id.Value)
+	}
+	assertSlicesEqual(t, []string{"Docker", "com.electron.dockerdesktop", "DockerHelper", "Updater", "com.docker.vmnetd"}, values)
+
+	// Data stored under a helper ID is matched and attributed to that helper
+	loginItemData := filepath.Join(fakeHome, "Library", "Application Support", "com.electron.dockerdesktop")
+	if err := os.MkdirAll(loginItemData, 0755); err != nil {
+		t.Fatal(err)
+	}
+	target := finder.Target{AppName: "Docker", BundleID: "com.docker.docker", Identifiers: embeddedIdentifiers(appPath, info)}
+	f := finder.NewTargetFinder(target, options.Options{Platform: finder.DARWIN})
+	var found *finder.Match
+	for _, match := range f.Matches {
+		if match.Path == loginItemData {
+			found = match
+		}
+	}
+	if found == nil || found.Identifier.Source != finder.SourceLoginItem || found.Rule != finder.RuleIdentifier {
+		t.Errorf("Expected %s to be matched by login item, got %+v", loginItemData, found)
+	}
+
+	// Generic executable names only match below the confirmation threshold,
+	// while the app's own name still matches by token
+	updater := filepath.Join(fakeHome, "Library", "Caches", "Updater")
+	named := filepath.Join(fakeHome, "Library", "Caches", "Docker")
+	for _, dir := range []string{updater, named} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f = finder.NewTargetFinder(target, options.Options{Platform: finder.DARWIN, NoCache: true})
+	scores := map[string]*finder.Match{}
+	for _, match := range f.Matches {
+		scores[match.Path] = match
+	}
+	if match := scores[updater]; match == nil || match.Confidence >= finder.LOW_CONFIDENCE {
+		t.Errorf("Expected %s to be matched below low confidence, got %+v", updater, match)
+	}
+	if match := scores[named]; match == nil || match.Rule != finder.RuleToken || match.Confidence < finder.LOW_CONFIDENCE {
+		t.Errorf("Expected %s to be matched by app name, got %+v", named, match)
+	}
+}
+
+func TestFinder_GroupContainers(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	groups := filepath.Join(fakeHome, "Library", "Group Containers")
+	expected := []string{
+		filepath.Join(groups, "ABCDE12345.com.vendor.shared"),
+		filepath.Join(groups, "ABCDE12345.group.com.vendor.app"),
+	}
+	unrelated := filepath.Join(groups, "ABCDE12345.com.vendor.shared.other")
+	for _, path := range append(expected, unrelated) {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target := finder.Target{
+		AppName:     "Vendor App",
+		BundleID:    "com.vendor.app",
+		TeamID:      "ABCDE12345",
+		Identifiers: finder.GroupIdentifiers("ABCDE12345", []string{"ABCDE12345.com.vendor.shared", "group.com.vendor.app"}, nil),
+	}
+	f := finder.NewTargetFinder(target, options.Options{Platform: finder.DARWIN})
+	assertSlicesEqual(t, expected, f.Paths())
+}
+
+func TestSharedGroups(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	applications := filepath.Join(fakeHome, "Applications")
+	writeInfoPlist(t, filepath.Join(applications, "Microsoft Word.app"), "com.microsoft.Word", "Microsoft Word")
+	writeInfoPlist(t, filepath.Join(applications, "Microsoft Excel.app"), "com.microsoft.Excel", "Microsoft Excel")
+	signatures := map[string]codesign.Signature{
+		"Microsoft Word.app":  {TeamID: "UBF8T346G9", AppGroups: []string{"UBF8T346G9.Office", "UBF8T346G9.com.microsoft.Word.data"}},
+		"Microsoft Excel.app": {TeamID: "UBF8T346G9", AppGroups: []string{"UBF8T346G9.Office"}},
+	}
+	readSignature = func(bundlePath, executable string) (codesign.Signature, error) {
+		if sig, ok := signatures[filepath.Base(bundlePath)]; ok {
+			return sig, nil
+		}
+		return codesign.Signature{}, codesign.ErrUnsigned
+	}
+	t.Cleanup(func() { readSignature = codesign.ReadBundle })
+
+	groups := filepath.Join(fakeHome, "Library", "Group Containers")
+	shared := filepath.Join(groups, "UBF8T346G9.Office")
+	own := filepath.Join(groups, "UBF8T346G9.com.microsoft.Word.data")
+	for _, dir := range []string{shared, own} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target, err := ResolveTarget("Microsoft Word", options.Options{Platform: finder.DARWIN, NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := finder.NewTargetFinder(target, options.Options{Platform: finder.DARWIN, NoCache: true})
+	scores := map[string]float64{}
+	for _, match := range f.Matches {
+		scores[match.Path] = match.Confidence
+	}
+	if score, ok := scores[shared]; !ok || score >= finder.LOW_CONFIDENCE {
+		t.Errorf("Expected group shared with Excel to be matched below low confidence, got %v", scores)
+	}
+	if scores[own] < finder.HIGH_CONFIDENCE {
+		t.Errorf("Expected Word's own group to be matched with high confidence, got %v", scores)
+	}
+
+	// Commands working on every installed app mark shared groups as well
+	for _, installed := range installedTargets(options.Options{Platform: finder.DARWIN, NoCache: true}) {
+		for _, id := range installed.Identifiers {
+			if id.Value == "UBF8T346G9.Office" && !id.Shared {
+				t.Errorf("Expected %s to mark the Office group shared", installed.AppName)
+			}
+		}
+	}
+}
+
+func writeContainerMetadata(t *testing.T, container, owner string) {
+	t.Helper()
+	if err := os.MkdirAll(container, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>MCMMetadataIdentifier</key><string>%s</string>
+</dict></plist>`, owner)
+	if err := os.WriteFile(filepath.Join(container, finder.ContainerMetadataFile), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFinder_ContainerMetadata(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	library := filepath.Join(fakeHome, "Library")
+	container := filepath.Join(library, "Containers", "6F1C2A4E-8B1D-4C1B-9E0A-3D2F5B7C9A10")
+	group := filepath.Join(library, "Group Containers", "0B3E7D52-2C4A-4F7E-8D19-6A5C1E2B3F40")
+	decoy := filepath.Join(library, "Containers", "com.gemini.test")
+	writeContainerMetadata(t, container, "com.gemini.test")
+	writeContainerMetadata(t, group, "group.com.gemini.test")
+	writeContainerMetadata(t, decoy, "com.other.app")
+
+	f := finder.NewFinder("MyTestApp", "com.gemini.test", options.Options{Platform: finder.DARWIN})
+	assertSlicesEqual(t, []string{container, group}, f.Paths())
+	owners := map[string]string{}
+	for _, match := range f.Matches {
+		owners[match.Path] = match.Owner
+	}
+	if owners[container] != "com.gemini.test" || owners[group] != "group.com.gemini.test" {
+		t.Errorf("Expected declared owners on matches, got %v", owners)
+	}
+
+	writeInfoPlist(t, filepath.Join(fakeHome, "Applications", "MyTestApp.app"), "com.gemini.test", "MyTestApp")
+	footprints := Footprints(options.Options{Platform: finder.DARWIN})
+	if len(footprints) != 1 {
+		t.Fatalf("Expected 1 footprint, got %d", len(footprints))
+	}
+	for _, footprint := range footprints {
+		var paths []string
+		for _, match := range footprint.Matches {
+			if match.Kind != finder.KindBundle {
+				paths = append(paths, match.Path)
+			}
+		}
+		assertSlicesEqual(t, []string{container, group}, paths)
+	}
+}
+
+func TestFinder_Confidence(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	support := filepath.Join(fakeHome, "Library", "Application Support")
+	scores := map[string]float64{
+		filepath.Join(support, "com.gemini.test"):        1.0,
+		filepath.Join(support, "com.gemini.test.helper"): 0.9,
+		filepath.Join(support, "MyTestApp"):              0.6,
+		filepath.Join(support, "Old MyTestApp Backups"):  0.3,
+	}
+	for path := range scores {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := finder.NewFinder("MyTestApp", "com.gemini.test", options.Options{Platform: finder.DARWIN})
+	if len(f.Matches) != len(scores) {
+		t.Fatalf("Expected %d matches, got %v", len(scores), f.Paths())
+	}
+	for _, match := range f.Matches {
+		if want := scores[match.Path]; match.Confidence != want {
+			t.Errorf("Confidence of %s = %.2f, want %.2f", match.Path, match.Confidence, want)
+		}
+	}
+
+	f = finder.NewFinder("MyTestApp", "com.gemini.test", options.Options{Platform: finder.DARWIN, MinConfidence: finder.LOW_CONFIDENCE})
+	if len(f.Matches) != 3 {
+		t.Errorf("Expected low confidence match to be dropped, got %v", f.Paths())
+	}
+}
+
+func TestExplain(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	support := filepath.Join(fakeHome, "Library", "Application Support")
+	matched := filepath.Join(support, "com.gemini.test", "state.json")
+	tooDeep := filepath.Join(support, "Vendor", "Nested", "MyTestApp")
+	unrelated := filepath.Join(support, "OtherApp")
+	for _, dir := range []string{filepath.Dir(matched), tooDeep, unrelated} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target := finder.Target{AppName: "MyTestApp", BundleID: "com.gemini.test"}
+	last := func(exp finder.Explanation) finder.Trace { return exp.Trace[len(exp.Trace)-1] }
+
+	exp := finder.Explain(target, matched, options.Options{Platform: finder.DARWIN})
+	if !exp.Matched || exp.Match.Rule != finder.RuleBundleID || exp.Match.Path != filepath.Dir(matched) {
+		t.Errorf("Expected %s to be matched through its parent by bundle ID, got %+v", matched, exp.Trace)
+	}
+
+	exp = finder.Explain(target, tooDeep, options.Options{Platform: finder.DARWIN})
+	if exp.Matched || last(exp).Outcome != finder.OutcomeSkipped || !strings.Contains(last(exp).Detail, finder.SkipDepthLimit) {
+		t.Errorf("Expected %s to be skipped by the depth limit, got %+v", tooDeep, exp.Trace)
+	}
+
+	exp = finder.Explain(target, unrelated, options.Options{Platform: finder.DARWIN})
+	if exp.Matched || last(exp).Outcome != finder.OutcomeNotMatched || len(last(exp).Checks) == 0 {
+		t.Errorf("Expected %s to list the failed rule checks, got %+v", unrelated, exp.Trace)
+	}
+
+	exp = finder.Explain(target, "/tmp/elsewhere", options.Options{Platform: finder.DARWIN})
+	if exp.Matched || exp.Root != "" {
+		t.Errorf("Expected path outside the search roots to have no root, got %s", exp.Root)
+	}
+}
+
+func TestFindOwners(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	docker := filepath.Join(fakeHome, "Applications", "Docker.app")
+	writeInfoPlist(t, docker, "com.docker.docker", "Docker")
+	helper := filepath.Join(docker, "Contents", "Library", "LaunchServices", "com.docker.vmnetd")
+	if err := os.MkdirAll(filepath.Dir(helper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(helper, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeInfoPlist(t, filepath.Join(fakeHome, "Applications", "Slack.app"), "com.tinyspeck.slackmacgap", "Slack")
+
+	owners := func(path string) []string {
+		var names []string
+		for _, owner := range FindOwners(path, options.Options{Platform: finder.DARWIN}) {
+			names = append(names, owner.Target.AppName)
+		}
+		return names
+	}
+
+	assertSlicesEqual(t, []string{"Docker"}, owners(filepath.Join(fakeHome, "Library", "LaunchAgents", "com.docker.vmnetd.plist")))
+	assertSlicesEqual(t, []string{"Slack"}, owners(filepath.Join(fakeHome, "Library", "Caches", "com.tinyspeck.slackmacgap.ShipIt")))
+	assertSlicesEqual(t, []string{"Docker"}, owners(filepath.Join(docker, "Contents", "MacOS", "Docker")))
+	if found := owners(filepath.Join(fakeHome, "Library", "Caches", "com.example.unknown")); len(found) != 0 {
+		t.Errorf("Expected no owners, got %v", found)
+	}
+
+	// Launchd jobs are attributed by the program they run when their label is unrelated
+	agents := filepath.Join(fakeHome, "Library", "LaunchAgents")
+	if err := os.MkdirAll(agents, 0755); err != nil {
+		t.Fatal(err)
+	}
+	job := filepath.Join(agents, "net.unrelated.helper.plist")
+	data := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>Label</key><string>net.unrelated.helper</string><key>Program</key><string>%s</string></dict></plist>`, helper)
+	if err := os.WriteFile(job, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertSlicesEqual(t, []string{"Docker"}, owners(job))
+}
+
+func TestFindOrphans(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	writeInfoPlist(t, filepath.Join(fakeHome, "Applications", "Slack.app"), "com.tinyspeck.slackmacgap", "Slack")
+
+	library := filepath.Join(fakeHome, "Library")
+	files := []string{
+		filepath.Join(library, "Preferences", "com.tinyspeck.slackmacgap.plist"),
+		filepath.Join(library, "Preferences", "com.old.editor.plist"),
+		filepath.Join(library, "Preferences", "ByHost", "com.old.editor.0A1B2C3D-0A1B-0A1B-0A1B-0A1B2C3D4E5F.plist"),
+		filepath.Join(library, "Preferences", "com.apple.finder.plist"),
+	}
+	dirs := []string{
+		filepath.Join(library, "Caches", "com.tinyspeck.slackmacgap.ShipIt"),
+		filepath.Join(library, "Caches", "com.old.editor"),
+		filepath.Join(library, "Caches", "Some Folder"),
+		filepath.Join(library, "Saved Application State", "com.old.editor.savedState"),
+		filepath.Join(library, "Group Containers", "ABCDE12345.com.gone.shared"),
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orphans := map[string][]string{}
+	for _, orphan := range FindOrphans(options.Options{Platform: finder.DARWIN}) {
+		orphans[orphan.BundleID] = finder.Paths(orphan.Matches)
+	}
+	if len(orphans) != 2 {
+		t.Fatalf("Expected orphans of com.old.editor and com.gone.shared, got %v", orphans)
+	}
+	assertSlicesEqual(t, []string{files[1], files[2], dirs[1], dirs[3]}, orphans["com.old.editor"])
+	assertSlicesEqual(t, []string{dirs[4]}, orphans["com.gone.shared"])
+}
+
+func TestListApps(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	appPath := filepath.Join(fakeHome, "Applications", "Tools", "MyTestApp.app")
+	writeInfoPlist(t, appPath, "com.gemini.test", "MyTestApp")
+	cache := filepath.Join(fakeHome, "Library", "Caches", "com.gemini.test")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cache, "data"), make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	apps := ListApps(true, options.Options{Platform: finder.DARWIN, Logical: true})
+	if len(apps) != 1 {
+		t.Fatalf("Expected 1 app, got %+v", apps)
+	}
+	app := apps[0]
+	if app.Name != "MyTestApp" || app.BundleID != "com.gemini.test" || app.Path != appPath {
+		t.Errorf("Unexpected app metadata %+v", app)
+	}
+	if app.Size == 0 || app.Footprint < app.Size+4096 {
+		t.Errorf("Expected footprint %d to include the bundle size %d and the cache", app.Footprint, app.Size)
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	apps := filepath.Join(fakeHome, "Applications")
+	writeInfoPlist(t, filepath.Join(apps, "MyTestApp.app"), "com.gemini.test", "MyTestApp")
+	writeInfoPlist(t, filepath.Join(apps, "Test Helper.app"), "com.gemini.test.helper", "Test Helper")
+	writeInfoPlist(t, filepath.Join(apps, "Slack.app"), "com.tinyspeck.slackmacgap", "Slack")
+
+	library := filepath.Join(fakeHome, "Library")
+	for _, dir := range []string{
+		filepath.Join(library, "Application Support", "com.gemini.test"),
+		filepath.Join(library, "Application Support", "com.gemini.test.helper"),
+		filepath.Join(library, "Application Support", "Slack"),
+		filepath.Join(library, "Caches", "com.tinyspeck.slackmacgap"),
+		filepath.Join(library, "Caches", "com.tinyspeck.slackmacgap.ShipIt"),
+		filepath.Join(library, "Preferences", "ByHost"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pref := filepath.Join(library, "Preferences", "ByHost", "com.tinyspeck.slackmacgap.0A1B2C3D.plist")
+	if err := os.WriteFile(pref, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	footprints := map[string][]string{}
+	for _, footprint := range Footprints(options.Options{Platform: finder.DARWIN}) {
+		footprints[footprint.Target.AppName] = finder.Paths(footprint.Matches)
+	}
+
+	// A single pass finds the same files as searching for each app on its own
+	slack := finder.NewTargetFinder(finder.Target{AppName: "Slack", BundleID: "com.tinyspeck.slackmacgap"}, options.Options{Platform: finder.DARWIN})
+	assertSlicesEqual(t, slack.Paths(), footprints["Slack"])
+
+	// Entries matching several apps go to the most specific one
+	assertSlicesEqual(t, []string{
+		filepath.Join(library, "Application Support", "com.gemini.test"),
+		filepath.Join(apps, "MyTestApp.app"),
+	}, footprints["MyTestApp"])
+	assertSlicesEqual(t, []string{
+		filepath.Join(library, "Application Support", "com.gemini.test.helper"),
+		filepath.Join(apps, "Test Helper.app"),
+	}, footprints["Test Helper"])
+}
+
+func TestAttributeJobsAndReceipts(t *testing.T) {
+	root := t.TempDir()
+	opts := options.Options{Platform: finder.DARWIN, Root: root, Home: "/Users/test"}
+
+	// The BOM lists Applications/FooApp.app, Library/Frameworks/FooKit.framework/FooKit and usr/local/bin/foo
+	appPath := filepath.Join(root, "Applications", "FooApp.app")
+	writeInfoPlist(t, appPath, "com.acme.foo", "FooApp")
+	framework := filepath.Join(root, "Library", "Frameworks", "FooKit.framework")
+	tool := filepath.Join(root, "usr", "local", "bin", "foo")
+	for _, file := range []string{filepath.Join(framework, "FooKit"), tool} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receiptsDir := filepath.Join(root, "var", "db", "receipts")
+	daemons := filepath.Join(root, "Library", "LaunchDaemons")
+	for _, dir := range []string{receiptsDir, daemons} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bomData, err := os.ReadFile(filepath.Join("testdata", "com.acme.pkg.FooApp.bom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>PackageIdentifier</key><string>com.acme.pkg.FooApp</string>
+<key>InstallPrefixPath</key><string>/</string>
+</dict></plist>`
+	job := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>Label</key><string>net.unrelated.helper</string><key>Program</key><string>%s/Contents/MacOS/helper</string></dict></plist>`, appPath)
+	for path, data := range map[string][]byte{
+		filepath.Join(receiptsDir, "com.acme.pkg.FooApp.plist"): []byte(receipt),
+		filepath.Join(receiptsDir, "com.acme.pkg.FooApp.bom"):   bomData,
+		filepath.Join(daemons, "net.unrelated.helper.plist"):    []byte(job),
+	} {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var footprint []string
+	for _, fp := range Footprints(opts) {
+		if fp.Target.AppName == "FooApp" {
+			footprint = finder.Paths(fp.Matches)
+		}
+	}
+
+	// Jobs running the app and the files of its package count towards its footprint
+	expected := []string{
+		appPath,
+		filepath.Join(daemons, "net.unrelated.helper.plist"),
+		framework,
+		tool,
+		filepath.Join(receiptsDir, "com.acme.pkg.FooApp.bom"),
+		filepath.Join(receiptsDir, "com.acme.pkg.FooApp.plist"),
+	}
+	sort.Strings(expected)
+	assertSlicesEqual(t, expected, footprint)
+
+	target := finder.Target{AppName: "FooApp", BundleID: "com.acme.foo", BundlePath: appPath}
+	assertSlicesEqual(t, finder.NewTargetFinder(target, opts).Paths(), footprint)
+}
+
+func TestMatchReceipts(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	// The BOM lists Applications/FooApp.app, Library/Frameworks/FooKit.framework/FooKit and usr/local/bin/foo
+	appPath := filepath.Join(fakeHome, "Applications", "FooApp.app")
+	writeInfoPlist(t, appPath, "com.acme.foo", "FooApp")
+	framework := filepath.Join(fakeHome, "Library", "Frameworks", "FooKit.framework")
+	tool := filepath.Join(fakeHome, "usr", "local", "bin", "foo")
+	for _, file := range []string{filepath.Join(framework, "FooKit"), tool} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receiptsDir := t.TempDir()
+	bomData, err := os.ReadFile(filepath.Join("testdata", "com.acme.pkg.FooApp.bom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>PackageIdentifier</key><string>com.acme.pkg.FooApp</string>
+<key>PackageVersion</key><string>1.0</string>
+<key>InstallPrefixPath</key><string>%s</string>
+</dict></plist>`, fakeHome)
+	for name, data := range map[string][]byte{
+		"com.acme.pkg.FooApp.plist":   []byte(receipt),
+		"com.acme.pkg.FooApp.bom":     bomData,
+		"com.apple.pkg.Unrelated.bom": bomData,
+	} {
+		if err := os.WriteFile(filepath.Join(receiptsDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receipts := finder.ReadReceipts(receiptsDir)
+	if len(receipts) != 1 || receipts[0].InstallPrefix != fakeHome {
+		t.Fatalf("Expected the single third party receipt, got %+v", receipts)
+	}
+
+	// The package lists the bundle, so everything else it installed belongs to the app
+	applications := []string{filepath.Join(fakeHome, "Applications")}
+	installed := finder.DiscoverBundles(applications, 0)
+	target := finder.Target{AppName: "FooApp", BundleID: "com.acme.foo", BundlePath: appPath}
+	matches := finder.MatchReceipts(target, receipts, installed, options.Options{})
+	assertSlicesEqual(t, []string{
+		framework,
+		tool,
+		filepath.Join(receiptsDir, "com.acme.pkg.FooApp.plist"),
+		filepath.Join(receiptsDir, "com.acme.pkg.FooApp.bom"),
+	}, finder.Paths(matches))
+	for _, match := range matches {
+		if match.Category != finder.CategoryReceipt || match.Confidence != 1.0 {
+			t.Errorf("Expected %s to be a full confidence receipt match, got %s %.2f", match.Path, match.Category, match.Confidence)
+		}
+	}
+
+	// Without the bundle the package is only claimed through its identifier
+	other := finder.Target{AppName: "Unrelated", BundleID: "com.other.app"}
+	if matches := finder.MatchReceipts(other, receipts, installed, options.Options{}); len(matches) != 0 {
+		t.Errorf("Expected no receipt matches for another app, got %v", finder.Paths(matches))
+	}
+
+	// The suite's BOM lists Applications/FooApp.app and Applications/BarApp.app besides the same files
+	suiteDir := t.TempDir()
+	suiteBOM, err := os.ReadFile(filepath.Join("testdata", "com.acme.pkg.Suite.bom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"com.acme.pkg.Suite.plist": []byte(strings.ReplaceAll(receipt, "com.acme.pkg.FooApp", "com.acme.pkg.Suite")),
+		"com.acme.pkg.Suite.bom":   suiteBOM,
+	} {
+		if err := os.WriteFile(filepath.Join(suiteDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	barApp := filepath.Join(fakeHome, "Applications", "BarApp.app")
+	writeInfoPlist(t, barApp, "com.acme.bar", "BarApp")
+
+	installed = finder.DiscoverBundles(applications, 0)
+	matches = finder.MatchReceipts(target, finder.ReadReceipts(suiteDir), installed, options.Options{Platform: finder.DARWIN})
+	assertSlicesEqual(t, []string{
+		framework,
+		tool,
+		filepath.Join(suiteDir, "com.acme.pkg.Suite.plist"),
+		filepath.Join(suiteDir, "com.acme.pkg.Suite.bom"),
+	}, finder.Paths(matches))
+	for _, match := range matches {
+		if match.Confidence >= finder.LOW_CONFIDENCE {
+			t.Errorf("Expected %s shared with BarApp to score below low confidence, got %.2f", match.Path, match.Confidence)
+		}
+	}
+
+	// The BOM lists ../outside next to usr/local/bin/foo, which must not escape the install prefix
+	escapeDir := t.TempDir()
+	escapeBOM, err := os.ReadFile(filepath.Join("testdata", "com.acme.pkg.Escape.bom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(escapeDir, "com.acme.pkg.Escape.bom"), escapeBOM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	escape := finder.Receipt{InstallPrefix: fakeHome, Path: filepath.Join(escapeDir, "com.acme.pkg.Escape.plist")}
+	files, err := escape.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSlicesEqual(t, []string{tool}, files)
+}
+
+func TestLaunchJobs(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+
+	appPath := filepath.Join(fakeHome, "Applications", "FooApp.app")
+	writeInfoPlist(t, appPath, "com.acme.foo", "FooApp")
+
+	agents := filepath.Join(fakeHome, "Library", "LaunchAgents")
+	if err := os.MkdirAll(agents, 0755); err != nil {
+		t.Fatal(err)
+	}
+	jobs := map[string]string{
+		"net.unrelated.updater": fmt.Sprintf(`<key>ProgramArguments</key><array><string>%s/Contents/MacOS/updater</string></array>`, appPath),
+		"net.unrelated.login":   `<key>BundleProgram</key><string>Contents/MacOS/login</string><key>AssociatedBundleIdentifiers</key><array><string>com.acme.foo</string></array>`,
+		"net.unrelated.other":   `<key>Program</key><string>/usr/local/bin/other</string>`,
+	}
+	for label, keys := range jobs {
+		data := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>Label</key><string>%s</string>%s</dict></plist>`, label, keys)
+		if err := os.WriteFile(filepath.Join(agents, label+".plist"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Jobs running from the bundle are matched even though their labels are unrelated
+	target := finder.Target{AppName: "FooApp", BundleID: "com.acme.foo", BundlePath: appPath}
+	var jobMatches []*finder.Match
+	for _, match := range finder.NewTargetFinder(target, options.Options{Platform: finder.DARWIN}).Matches {
+		if match.Rule == finder.RuleLaunchJob {
+			jobMatches = append(jobMatches, match)
+		}
+	}
+	assertSlicesEqual(t, []string{
+		filepath.Join(agents, "net.unrelated.login.plist"),
+		filepath.Join(agents, "net.unrelated.updater.plist"),
+	}, finder.Paths(jobMatches))
+
+	// Jobs are booted out before their plists are removed
+	useJournalDir(t)
+	fake := &launchd.Fake{}
+	previous := deleter.Launchd
+	deleter.Launchd = fake
+	t.Cleanup(func() { deleter.Launchd = previous })
+
+	d := deleter.NewDeleter(jobMatches, options.Options{Mode: true})
+	if err := d.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	var targets []string
+	for _, call := range fake.Calls {
+		targets = append(targets, strings.Join(call, " "))
+	}
+	uid := os.Getuid()
+	assertSlicesEqual(t, []string{
+		fmt.Sprintf("bootout gui/%d/net.unrelated.login", uid),
+		fmt.Sprintf("bootout gui/%d/net.unrelated.updater", uid),
+	}, targets)
+	for _, match := range jobMatches {
+		if _, err := os.Stat(match.Path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", match.Path)
+		}
+	}
+}
+
+func TestDeleterRunningProcesses(t *testing.T) {
+	useJournalDir(t)
+	fake := &process.Fake{}
+	previous := deleter.Processes
+	deleter.Processes = fake
+	t.Cleanup(func() { deleter.Processes = previous })
+
+	// Aborting at the prompt leaves the files and the process alone
+	files := makeTestFiles(t, 1, "test-running")
+	fake.Processes = []process.Process{{PID: 4242, Executable: files[0]}}
+	prompt.Input = strings.NewReader("2\n")
+	t.Cleanup(func() { prompt.Input = os.Stdin })
+
+	d := deleter.NewDeleter([]*finder.Match{finder.NewMatch(files[0])}, options.Options{Mode: true})
+	if err := d.Delete(); !errors.Is(err, deleter.ErrAborted) {
+		t.Fatalf("Expected removal to be aborted, got %v", err)
+	}
+	if _, err := os.Stat(files[0]); err != nil || len(fake.Signals) != 0 {
+		t.Errorf("Expected nothing to be removed or signaled after aborting, got %v %v", err, fake.Signals)
+	}
+
+	// --kill quits without asking
+	d = deleter.NewDeleter([]*finder.Match{finder.NewMatch(files[0])}, options.Options{Mode: true, Kill: true})
+	if err := d.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Processes) != 0 || len(fake.Signals[4242]) == 0 {
+		t.Errorf("Expected process to be quit, got %v", fake.Processes)
+	}
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", files[0])
+	}
+}
+
+// Builds a fixture home holding an app bundle, its data and an unrelated app's cache
+func setupEndToEnd(t *testing.T) (string, []string, string) {
+	t.Helper()
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(fakeHome, ".local", "share"))
+
+	appPath := filepath.Join(fakeHome, "Applications", "MyTestApp.app")
+	writeInfoPlist(t, appPath, "com.gemini.test", "MyTestApp")
+	library := filepath.Join(fakeHome, "Library")
+	container := filepath.Join(library, "Containers", "6F1C2A4E-8B1D-4C1B-9E0A-3D2F5B7C9A10")
+	writeContainerMetadata(t, container, "com.gemini.test")
+
+	files := map[string]int{
+		filepath.Join(appPath, "Contents", "MacOS", "MyTestApp"):                 32 * 1024,
+		filepath.Join(library, "Application Support", "com.gemini.test", "data"): 16 * 1024,
+		filepath.Join(library, "Caches", "com.gemini.test", "cache"):             8 * 1024,
+		filepath.Join(library, "Preferences", "com.gemini.test.plist"):           100,
+		filepath.Join(library, "Caches", "com.other.app", "cache"):               8 * 1024,
+	}
+	for path, size := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		appPath,
+		filepath.Join(library, "Application Support", "com.gemini.test"),
+		filepath.Join(library, "Caches", "com.gemini.test"),
+		container,
+		filepath.Join(library, "Preferences", "com.gemini.test.plist"),
+	}
+	return fakeHome, expected, filepath.Join(library, "Caches", "com.other.app")
+}
+
+func TestEndToEnd(t *testing.T) {
+	useJournalDir(t)
+	useManifestDir(t)
+
+	t.Run("Trash and restore", func(t *testing.T) {
+		fakeHome, expected, unrelated := setupEndToEnd(t)
+		opts := options.Options{Platform: finder.DARWIN, NoCache: true, Trash: trash.FREEDESKTOP}
+
+		instance := NewResolver("MyTestApp", opts)
+		assertSlicesEqual(t, expected, instance.Finder.Paths())
+		for _, match := range instance.Finder.Matches {
+			if match.Size(false) == 0 {
+				t.Errorf("Expected an allocated size for %s", match.Path)
+			}
+		}
+
+		if err := instance.Deleter.Delete(); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		assertExist(t, false, expected...)
+		assertExist(t, true, unrelated)
+		trashed, _ := os.ReadDir(filepath.Join(fakeHome, ".local", "share", "Trash", "files"))
+		infos, _ := os.ReadDir(filepath.Join(fakeHome, ".local", "share", "Trash", "info"))
+		if len(trashed) != len(expected) || len(infos) != len(expected) {
+			t.Fatalf("Expected %d items with trash info in the Trash, got %d and %d", len(expected), len(trashed), len(infos))
+		}
+
+		manifest, err := deleter.FindManifest("MyTestApp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bin, err := trash.ByName(manifest.Backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range manifest.Restore(bin) {
+			if result.Err != nil {
+				t.Errorf("Restoring %s failed: %v", result.Item.Original, result.Err)
+			}
+		}
+		assertExist(t, true, expected...)
+		assertExist(t, true, filepath.Join(expected[0], "Contents", "MacOS", "MyTestApp"))
+	})
+
+	t.Run("Force", func(t *testing.T) {
+		_, expected, unrelated := setupEndToEnd(t)
+		opts := options.Options{Platform: finder.DARWIN, NoCache: true, Mode: true}
+
+		instance := NewResolver("com.gemini.test", opts)
+		if err := instance.Deleter.Delete(); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		assertExist(t, false, expected...)
+		assertExist(t, true, unrelated)
+		if len(instance.Deleter.Results()) != len(instance.Finder.Matches) {
+			t.Errorf("Expected a result per match, got %d for %d matches", len(instance.Deleter.Results()), len(instance.Finder.Matches))
+		}
+		for _, result := range instance.Deleter.Results() {
+			if result.Outcome != deleter.OutcomeDeleted || result.Err != nil {
+				t.Errorf("Expected %s to be deleted, got %s (%v)", result.Match.Path, result.Outcome, result.Err)
+			}
+		}
+		for _, path := range expected {
+			if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), deleter.STAGING_DIR+"*")); len(matches) != 0 {
+				t.Errorf("Expected staging to be purged, found %v", matches)
+			}
+		}
+	})
+}
+
+func TestWriteReport(t *testing.T) {
+	useJournalDir(t)
+	useManifestDir(t)
+	fakeHome, expected, _ := setupEndToEnd(t)
+
+	// A second copy and a low confidence match make the removal prompt twice
+	copyPath := filepath.Join(fakeHome, "Applications", "Beta", "MyTestApp.app")
+	writeInfoPlist(t, copyPath, "com.gemini.test", "MyTestApp")
+	backups := filepath.Join(fakeHome, "Library", "Application Support", "Old MyTestApp Backups")
+	if err := os.MkdirAll(backups, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt.Input = strings.NewReader("3\ny\n")
+	var messages strings.Builder
+	prompt.Output = &messages
+	t.Cleanup(func() {
+		prompt.Input = os.Stdin
+		prompt.Output = os.Stdout
+	})
+
+	// Anything written to stdout besides the report would break parsing it
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+	read := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		read <- data
+	}()
+
+	opts := options.Options{Platform: finder.DARWIN, NoCache: true, Mode: true, Output: output.JSON}
+	instance := NewResolver("MyTestApp", opts)
+	reportErr := instance.WriteReport(os.Stdout)
+	w.Close()
+	os.Stdout = stdout
+	data := <-read
+	if reportErr != nil {
+		t.Fatalf("WriteReport failed: %v", reportErr)
+	}
+
+	var report output.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Expected stdout to be a single JSON document, got %v:\n%s", err, data)
+	}
+	if !strings.Contains(messages.String(), "Which should be removed?") || !strings.Contains(messages.String(), "Remove these too?") {
+		t.Errorf("Expected both prompts on the message output, got:\n%s", messages.String())
+	}
+	if report.Kind != output.KindRemove || report.BundleID != "com.gemini.test" {
+		t.Errorf("Unexpected report header %+v", report)
+	}
+	if len(report.Results) != len(expected)+2 {
+		t.Errorf("Expected a result per match, got %+v", report.Results)
+	}
+	for _, result := range report.Results {
+		if result.Outcome != deleter.OutcomeDeleted {
+			t.Errorf("Expected %s to be deleted, got %s %s", result.Path, result.Outcome, result.Error)
+		}
+	}
+	assertExist(t, false, append(expected, copyPath, backups)...)
+}
+
+func TestLinux(t *testing.T) {
+	fakeHome := t.TempDir()
+	t.Setenv("HOME", fakeHome)
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(env, "")
+	}
+
+	entry := "[Desktop Entry]\nType=Application\nName=Test App\nExec=testapp %U\n"
+	files := map[string]string{
+		filepath.Join(fakeHome, ".local", "share", "applications", "org.gemini.TestApp.desktop"): entry,
+		filepath.Join(fakeHome, ".local", "share", "applications", "other.desktop"):              "[Desktop Entry]\nName=Other\nExec=other\n",
+		filepath.Join(fakeHome, ".config", "autostart", "org.gemini.TestApp.desktop"):            entry,
+		filepath.Join(fakeHome, ".config", "testapp", "settings.ini"):                            "",
+		filepath.Join(fakeHome, ".config", "other", "settings.ini"):                              "",
+		filepath.Join(fakeHome, ".cache", "org.gemini.TestApp", "cache"):                         "",
+		filepath.Join(fakeHome, ".local", "state", "org.gemini.TestApp", "history"):              "",
+		filepath.Join(fakeHome, ".var", "app", "org.gemini.TestApp", "data", "db"):               "",
+	}
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := options.Options{Platform: finder.LINUX, NoCache: true}
+	desktopEntry := filepath.Join(fakeHome, ".local", "share", "applications", "org.gemini.TestApp.desktop")
+	for _, query := range []string{"org.gemini.TestApp", "Test App", desktopEntry} {
+		target, err := ResolveTarget(query, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target.BundlePath != desktopEntry || target.BundleID != "org.gemini.TestApp" {
+			t.Fatalf("Expected %q to resolve to %s, got %+v", query, desktopEntry, target)
+		}
+		if len(target.Identifiers) != 1 || target.Identifiers[0].Value != "testapp" || target.Identifiers[0].Source != finder.SourceExecutable {
+			t.Errorf("Expected the executable as the only identifier, got %v", target.Identifiers)
+		}
+	}
+
+	target, _ := ResolveTarget("org.gemini.TestApp", opts)
+	f := finder.NewTargetFinder(target, opts)
+	assertSlicesEqual(t, []string{
+		desktopEntry,
+		filepath.Join(fakeHome, ".config", "autostart", "org.gemini.TestApp.desktop"),
+		filepath.Join(fakeHome, ".config", "testapp"),
+		filepath.Join(fakeHome, ".cache", "org.gemini.TestApp"),
+		filepath.Join(fakeHome, ".local", "state", "org.gemini.TestApp"),
+		filepath.Join(fakeHome, ".var", "app", "org.gemini.TestApp"),
+	}, f.Paths())
+	for _, match := range f.Matches {
+		if match.Path == filepath.Join(fakeHome, ".var", "app", "org.gemini.TestApp") && match.Category != "Flatpak Data" {
+			t.Errorf("Expected Flatpak data to be categorized as such, got %q", match.Category)
+		}
+	}
+
+	candidates := finder.InferBundleIDs("TestApp", opts)
+	if len(candidates) == 0 || candidates[0].BundleID != "org.gemini.TestApp" {
+		t.Errorf("Expected org.gemini.TestApp to be inferred, got %v", candidates)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	useJournalDir(t)
+	useManifestDir(t)
+	root := t.TempDir()
+	localHome := t.TempDir()
+	t.Setenv("HOME", localHome)
+	home := filepath.Join(root, "Users", "alice")
+
+	// The BOM lists Applications/FooApp.app, Library/Frameworks/FooKit.framework/FooKit and usr/local/bin/foo
+	appPath := filepath.Join(root, "Applications", "FooApp.app")
+	writeInfoPlist(t, appPath, "com.acme.foo", "FooApp")
+	bomData, err := os.ReadFile(filepath.Join("testdata", "com.acme.pkg.FooApp.bom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts := filepath.Join(root, "var", "db", "receipts")
+	files := map[string][]byte{
+		filepath.Join(root, "Library", "Frameworks", "FooKit.framework", "FooKit"):   nil,
+		filepath.Join(root, "usr", "local", "bin", "foo"):                            nil,
+		filepath.Join(root, "Library", "Application Support", "com.acme.foo", "db"):  nil,
+		filepath.Join(root, "Library", "Caches", "com.acme.foo", "cache"):            nil,
+		filepath.Join(root, "Library", "LaunchDaemons", "com.acme.foo.helper.plist"): nil,
+		filepath.Join(home, "Library", "Preferences", "com.acme.foo.plist"):          nil,
+		filepath.Join(localHome, "Library", "Caches", "com.acme.foo", "cache"):       nil,
+		filepath.Join(receipts, "com.acme.pkg.FooApp.bom"):                           bomData,
+		filepath.Join(receipts, "com.acme.pkg.FooApp.plist"): []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>PackageIdentifier</key><string>com.acme.pkg.FooApp</string>
+<key>InstallPrefixPath</key><string>/</string>
+</dict></plist>`),
+	}
+	for path, data := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the rebased system and home are searched, never the local home
+	opts := options.Options{Platform: finder.DARWIN, Root: root, Home: "/Users/alice", NoCache: true, Mode: true}
+	expected := []string{
+		appPath,
+		filepath.Join(root, "Library", "Frameworks", "FooKit.framework"),
+		filepath.Join(root, "usr", "local", "bin", "foo"),
+		filepath.Join(root, "Library", "Application Support", "com.acme.foo"),
+		filepath.Join(root, "Library", "Caches", "com.acme.foo"),
+		filepath.Join(root, "Library", "LaunchDaemons", "com.acme.foo.helper.plist"),
+		filepath.Join(home, "Library", "Preferences", "com.acme.foo.plist"),
+		filepath.Join(receipts, "com.acme.pkg.FooApp.plist"),
+		filepath.Join(receipts, "com.acme.pkg.FooApp.bom"),
+	}
+	instance := NewResolver("com.acme.foo", opts)
+	assertSlicesEqual(t, expected, instance.Finder.Paths())
+
+	// Nothing is removed from a rebased system unless allowed
+	if err := instance.Deleter.Delete(); !errors.Is(err, deleter.ErrReadOnlyRoot) {
+		t.Fatalf("Expected ErrReadOnlyRoot, got %v", err)
+	}
+	assertExist(t, true, expected...)
+
+	fake := &launchd.Fake{}
+	previous := deleter.Launchd
+	deleter.Launchd = fake
+	t.Cleanup(func() { deleter.Launchd = previous })
+	opts.AllowDelete = true
+	instance = NewResolver("com.acme.foo", opts)
+	if err := instance.Deleter.Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	assertExist(t, false, expected[:7]...) // the receipts are matched by name alone and need confirming
+	assertExist(t, true, filepath.Join(localHome, "Library", "Caches", "com.acme.foo"))
+	if len(fake.Calls) != 0 {
+		t.Errorf("Expected no launchd jobs of the running system to be unloaded, got %v", fake.Calls)
+	}
 }
@@ -0,0 +1,206 @@
+package resolver
+
+/*
+Target.go holds the logic for turning the command line target, which may be
+an app name, a bundle ID or a path, into the owning application bundle
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alewtschuk/rmapp/desktop"
+	"github.com/alewtschuk/rmapp/finder"
+	"github.com/alewtschuk/rmapp/options"
+)
+
+// Resolution is what a command line target resolves to
+type resolution struct {
+	app      string // app name used for searching
+	appPath  string // path of the owning bundle, which may not exist
+	explicit bool   // the target was a path, which selects a single copy of the app
+
+	installed  []finder.Bundle // bundles under the application roots, walked at most once
+	discovered bool
+	groups     func() groupInventory // groups of the installed bundles, read at most once
+}
+
+// Returns every installed bundle, walking the application roots on first use
+func (r *resolution) installedBundles(opts options.Options) []finder.Bundle {
+	if !r.discovered {
+		r.installed = finder.DiscoverInstalled(opts)
+		r.discovered = true
+	}
+	return r.installed
+}
+
+// Returns a function reading the groups of the installed bundles on first use
+func (r *resolution) installedGroups(opts options.Options) func() groupInventory {
+	if r.groups == nil {
+		r.groups = sync.OnceValue(func() groupInventory {
+			return readGroupInventory(r.installedBundles(opts), opts)
+		})
+	}
+	return r.groups
+}
+
+// Resolves the target into the app name used for searching and the
+// path of the owning .app bundle.
+//
+// Bundle IDs that are not installed switch to leftovers mode by setting
+// opts.BundleID, in which case the returned path does not exist
+func resolveTarget(target string, opts *options.Options) (*resolution, error) {
+	r := &resolution{app: target}
+	if isPathTarget(target) {
+		path, err := targetPath(target, *opts)
+		if err != nil {
+			return nil, err
+		}
+		appPath, err := owningBundle(path)
+		if err != nil {
+			return nil, err
+		}
+		r.app, r.appPath, r.explicit = bundleName(appPath), appPath, true
+		return r, nil
+	}
+
+	r.appPath = getAppPath(getDotApp(target), r, *opts)
+	if finder.IsBundleID(target) && !bundleExists(r.appPath) {
+		if appPath := findBundleByID(target, r, *opts); appPath != "" {
+			r.app, r.appPath = bundleName(appPath), appPath
+			return r, nil
+		}
+		if opts.BundleID == "" {
+			opts.BundleID = target
+		}
+	}
+	return r, nil
+}
+
+// Reports if the target should be treated as a filesystem path rather than a name
+func isPathTarget(target string) bool {
+	return strings.ContainsRune(target, os.PathSeparator) || strings.HasPrefix(target, "~") || strings.HasPrefix(target, ".")
+}
+
+// Returns where the path target is found, expanding ~ and rebasing
+// absolute paths onto the root, which they name the contents of
+func targetPath(target string, opts options.Options) (string, error) {
+	if opts.Root == "" {
+		return expandHome(target)
+	}
+
+	name, rest, _ := strings.Cut(strings.TrimPrefix(target, "~"), "/")
+	switch {
+	case strings.HasPrefix(target, "~") && name == "":
+		return filepath.Join(finder.HomeDir(opts), rest), nil
+	case strings.HasPrefix(target, "~"):
+		return "", fmt.Errorf("cannot expand ~%s under --root, use '--home' or an absolute path", name)
+	case filepath.IsAbs(target) && !isBelow(target, opts.Root):
+		return finder.Rebase(target, opts), nil
+	}
+	return target, nil
+}
+
+// Checks if path is dir or lives inside it
+func isBelow(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
+// Returns the outermost .app bundle containing path after following symlinks,
+// or the desktop entry path resolves to.
+//
+// A path to a .app that no longer exists is returned as is so its leftovers can be searched
+func owningBundle(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		if os.IsNotExist(err) && (strings.HasSuffix(abs, ".app") || desktop.IsEntryPath(abs)) {
+			return abs, nil
+		}
+		return "", err
+	}
+
+	// Desktop entries stand in for the bundle of Linux apps
+	if desktop.IsEntryPath(resolved) {
+		return resolved, nil
+	}
+
+	// Walk up to the root, keeping the outermost bundle so helpers resolve to their host app
+	var bundle string
+	for p := resolved; ; p = filepath.Dir(p) {
+		if strings.HasSuffix(p, ".app") {
+			bundle = p
+		}
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+
+	if bundle == "" {
+		return "", fmt.Errorf("%s is not inside an .app bundle", resolved)
+	}
+	return bundle, nil
+}
+
+// Expands a leading ~ or ~/ to $HOME and ~user to that user's home directory
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	name, rest, _ := strings.Cut(strings.TrimPrefix(path, "~"), "/")
+	if name == "" {
+		return filepath.Join(os.Getenv("HOME"), rest), nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("cannot expand ~%s: %w", name, err)
+	}
+	return filepath.Join(u.HomeDir, rest), nil
+}
+
+// Searches the application roots for a bundle with the given identifier,
+// falling back to a Spotlight query of the running system. Returns "" if
+// the bundle is not installed
+func findBundleByID(bundleID string, r *resolution, opts options.Options) string {
+	if copies := finder.BundlesWithID(r.installedBundles(opts), bundleID); len(copies) > 0 {
+		return copies[0].Path
+	}
+	if opts.Root != "" {
+		return ""
+	}
+	return mdfindBundleID(bundleID)
+}
+
+// Asks Spotlight for an .app bundle with the given identifier
+func mdfindBundleID(bundleID string) string {
+	query := fmt.Sprintf("kMDItemCFBundleIdentifier == '%s'", strings.ReplaceAll(bundleID, "'", ""))
+	out, err := exec.Command("mdfind", query).Output()
+	if err != nil {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasSuffix(line, ".app") {
+			return line
+		}
+	}
+	return ""
+}
+
+// Returns the app name of a bundle path (e.g. "/Applications/Slack.app" to "Slack")
+func bundleName(appPath string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(filepath.Base(appPath), ".app"), desktop.EXTENSION)
+}
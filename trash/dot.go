@@ -0,0 +1,82 @@
+package trash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Dot moves files into the macOS Trash folders directly, as used when rmapp
+// is built without cgo and NSFileManager is not available.
+//
+// Files on the home volume go to ~/.Trash, files on other volumes to the
+// volume's .Trashes/$uid. Unlike the Finder it records no Put Back location,
+// restoring relies on the run's manifest
+type Dot struct {
+	Home string // ~/.Trash of the user
+	UID  int    // user owning the per volume trash directories
+}
+
+// Creates the Trash of the current user
+func NewDot() Dot {
+	return Dot{Home: filepath.Join(os.Getenv("HOME"), ".Trash"), UID: os.Getuid()}
+}
+
+// Shares the native name as both move items into the same Trash folders
+func (d Dot) Name() string {
+	return NATIVE
+}
+
+// Moves the path into the Trash of its volume, naming it like the Finder
+// does when the Trash already holds an item with the same name
+func (d Dot) MoveToTrash(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	dir, err := d.trashDir(path)
+	if err != nil {
+		return "", err
+	}
+
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for i := 1; i <= MAX_ATTEMPTS; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s %d%s", stem, i, ext)
+		}
+		trashed := filepath.Join(dir, name)
+		if _, err := os.Lstat(trashed); err == nil {
+			continue
+		}
+		if err := os.Rename(path, trashed); err != nil {
+			return "", err
+		}
+		return trashed, nil
+	}
+	return "", fmt.Errorf("no free name for %s in the Trash", base)
+}
+
+func (d Dot) Restore(trashed, original string) error {
+	return renameBack(trashed, original)
+}
+
+// Returns the Trash folder on the volume of path, creating it if needed
+func (d Dot) trashDir(path string) (string, error) {
+	dev, ok := deviceOf(path)
+	if !ok {
+		return "", fmt.Errorf("could not stat %s", path)
+	}
+	dir := d.Home
+	if homeDev, ok := deviceOf(existingAncestor(d.Home)); !ok || homeDev != dev {
+		dir = filepath.Join(VolumeRoot(path), ".Trashes", strconv.Itoa(d.UID))
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("no Trash available on the volume of %s: %w", path, err)
+	}
+	return dir, nil
+}
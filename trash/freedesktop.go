@@ -0,0 +1,183 @@
+package trash
+
+/*
+Freedesktop.go implements the freedesktop.org Trash specification. Files on
+the home volume go to $XDG_DATA_HOME/Trash, files on other volumes to the
+volume's shared $topdir/.Trash/$uid or its own $topdir/.Trash-$uid. Every
+trashed file gets a .trashinfo file recording where it came from so desktop
+file managers can restore it too
+*/
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	INFO_SUFFIX  string = ".trashinfo"          // suffix of the files describing trashed items
+	INFO_HEADER  string = "[Trash Info]"        // first line of every .trashinfo file
+	DATE_LAYOUT  string = "2006-01-02T15:04:05" // DeletionDate format in local time
+	MAX_ATTEMPTS int    = 1000                  // names tried before giving up on a free one
+)
+
+// Freedesktop moves files to the freedesktop.org Trash
+type Freedesktop struct {
+	Home string // home trash directory
+	UID  int    // user owning the per volume trash directories
+}
+
+// Creates the freedesktop.org Trash of the current user
+func NewFreedesktop() Freedesktop {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(os.Getenv("HOME"), ".local", "share")
+	}
+	return Freedesktop{Home: filepath.Join(dataHome, "Trash"), UID: os.Getuid()}
+}
+
+func (f Freedesktop) Name() string {
+	return FREEDESKTOP
+}
+
+// Moves the path into the trash of its volume.
+//
+// The .trashinfo file is created exclusively first, which reserves the
+// name, and is removed again if the file itself cannot be moved
+func (f Freedesktop) MoveToTrash(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	dir, topdir, err := f.trashDir(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Paths in a volume trash are relative to the volume so they survive remounting
+	recorded := path
+	if topdir != "" {
+		if rel, err := filepath.Rel(topdir, path); err == nil {
+			recorded = rel
+		}
+	}
+	info := fmt.Sprintf("%s\nPath=%s\nDeletionDate=%s\n", INFO_HEADER, (&url.URL{Path: recorded}).EscapedPath(), time.Now().Format(DATE_LAYOUT))
+
+	name, err := reserveName(filepath.Join(dir, "info"), filepath.Base(path), info)
+	if err != nil {
+		return "", err
+	}
+	trashed := filepath.Join(dir, "files", name)
+	if err := os.Rename(path, trashed); err != nil {
+		os.Remove(infoPath(trashed))
+		return "", err
+	}
+	return trashed, nil
+}
+
+// Moves the item back and removes its .trashinfo file
+func (f Freedesktop) Restore(trashed, original string) error {
+	if err := renameBack(trashed, original); err != nil {
+		return err
+	}
+	if err := os.Remove(infoPath(trashed)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Returns the trash directory for path and the top directory of its
+// volume, which is "" for the home trash.
+//
+// The home trash is used for everything on the home volume. Other volumes
+// use the shared .Trash/$uid when an administrator has set one up, as a
+// sticky directory that is no symlink, and their own .Trash-$uid otherwise
+func (f Freedesktop) trashDir(path string) (string, string, error) {
+	dev, ok := deviceOf(path)
+	if !ok {
+		return "", "", fmt.Errorf("could not stat %s", path)
+	}
+	if homeDev, ok := deviceOf(existingAncestor(f.Home)); ok && homeDev == dev {
+		return f.Home, "", ensureTrash(f.Home)
+	}
+
+	topdir := VolumeRoot(path)
+	shared := filepath.Join(topdir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, strconv.Itoa(f.UID))
+		if err := ensureTrash(dir); err == nil {
+			return dir, topdir, nil
+		}
+	}
+
+	dir := filepath.Join(topdir, fmt.Sprintf(".Trash-%d", f.UID))
+	if err := ensureTrash(dir); err != nil {
+		return "", "", fmt.Errorf("no trash available on the volume of %s: %w", path, err)
+	}
+	return dir, topdir, nil
+}
+
+// Creates the files and info directories of a trash
+func ensureTrash(dir string) error {
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes the .trashinfo file under the first free name, trying
+// "name", "name.2", "name.3" and so on. Returns the name taken
+func reserveName(infoDir, base, info string) (string, error) {
+	for i := 1; i <= MAX_ATTEMPTS; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d", base, i)
+		}
+		if _, err := os.Lstat(filepath.Join(filepath.Dir(infoDir), "files", name)); err == nil {
+			continue // left behind without its info file
+		}
+		file, err := os.OpenFile(filepath.Join(infoDir, name+INFO_SUFFIX), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = file.WriteString(info)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(file.Name())
+			return "", err
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("no free name for %s in the trash", base)
+}
+
+// Returns the .trashinfo file of an item in the files directory of a trash
+func infoPath(trashed string) string {
+	dir := filepath.Dir(filepath.Dir(trashed))
+	return filepath.Join(dir, "info", filepath.Base(trashed)+INFO_SUFFIX)
+}
+
+// Returns the closest ancestor of path that exists, or path itself
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Lstat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,115 @@
+package trash
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Memory is an in memory trash. Used in tests
+//
+// Trashed files are read into memory and removed from disk, so nothing
+// ends up in the real trash of the machine running the tests
+type Memory struct {
+	mu        sync.Mutex
+	Originals map[string]string // original path of every item still in the trash
+	Errs      map[string]error  // errors returned when trashing a path
+	items     map[string][]memoryEntry
+	trashed   int // items trashed so far, numbering them uniquely even after restores
+}
+
+// A single file, folder or symlink of a trashed item
+type memoryEntry struct {
+	rel  string
+	mode fs.FileMode
+	data []byte
+	link string
+}
+
+func (m *Memory) Name() string {
+	return MEMORY
+}
+
+// Reads the path into memory and removes it from disk
+func (m *Memory) MoveToTrash(path string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.Errs[path]; err != nil {
+		return "", err
+	}
+
+	var entries []memoryEntry
+	err := filepath.WalkDir(path, func(subPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(path, subPath)
+		entry := memoryEntry{rel: rel, mode: info.Mode()}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			entry.link, err = os.Readlink(subPath)
+		case info.Mode().IsRegular():
+			entry.data, err = os.ReadFile(subPath)
+		}
+		entries = append(entries, entry)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return "", err
+	}
+
+	if m.items == nil {
+		m.items = map[string][]memoryEntry{}
+		m.Originals = map[string]string{}
+	}
+	m.trashed++
+	trashed := fmt.Sprintf("memory:%d/%s", m.trashed, filepath.Base(path))
+	m.items[trashed] = entries
+	m.Originals[trashed] = path
+	return trashed, nil
+}
+
+// Writes the item back to disk, parents before their contents
+func (m *Memory) Restore(trashed, original string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries, ok := m.items[trashed]
+	if !ok {
+		return ErrNotInTrash
+	}
+	if _, err := os.Lstat(original); err == nil {
+		return ErrOccupied
+	}
+	if err := os.MkdirAll(filepath.Dir(original), 0755); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(original, entry.rel)
+		var err error
+		switch {
+		case entry.mode.IsDir():
+			err = os.Mkdir(path, entry.mode.Perm())
+		case entry.mode&os.ModeSymlink != 0:
+			err = os.Symlink(entry.link, path)
+		default:
+			err = os.WriteFile(path, entry.data, entry.mode.Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	delete(m.items, trashed)
+	delete(m.Originals, trashed)
+	return nil
+}
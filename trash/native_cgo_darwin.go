@@ -0,0 +1,33 @@
+//go:build darwin && cgo
+
+package trash
+
+import (
+	"fmt"
+
+	"github.com/alewtschuk/rmapp/darwin"
+)
+
+// nativeTrash moves files to the user's Trash through NSFileManager
+type nativeTrash struct{}
+
+// Returns the NSFileManager backed Trash
+func Native() (Trash, error) {
+	return nativeTrash{}, nil
+}
+
+func (nativeTrash) Name() string {
+	return NATIVE
+}
+
+func (nativeTrash) MoveToTrash(path string) (string, error) {
+	trashed, ok := darwin.MoveFileToTrash(path)
+	if !ok {
+		return "", fmt.Errorf("could not move %s to the Trash", path)
+	}
+	return trashed, nil
+}
+
+func (nativeTrash) Restore(trashed, original string) error {
+	return renameBack(trashed, original)
+}
@@ -0,0 +1,8 @@
+//go:build darwin && !cgo
+
+package trash
+
+// Returns the Trash folders moved into directly, as NSFileManager needs cgo
+func Native() (Trash, error) {
+	return NewDot(), nil
+}
@@ -0,0 +1,8 @@
+//go:build !darwin
+
+package trash
+
+// Returns ErrUnsupported as the NSFileManager Trash only exists on macOS
+func Native() (Trash, error) {
+	return nil, ErrUnsupported
+}
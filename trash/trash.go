@@ -0,0 +1,75 @@
+// Package trash moves files to a trash they can be restored from, using the
+// native macOS Trash or the freedesktop.org Trash used by Linux desktops.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Names of the trash backends
+const (
+	NATIVE      string = "native"      // NSFileManager Trash on macOS
+	FREEDESKTOP string = "freedesktop" // freedesktop.org Trash specification
+	MEMORY      string = "memory"      // in memory trash used in tests
+)
+
+var (
+	// ErrNotInTrash is returned when a trashed item is no longer in the trash
+	ErrNotInTrash = errors.New("no longer in the Trash")
+	// ErrOccupied is returned when something new occupies the original path of a trashed item
+	ErrOccupied = errors.New("original location is occupied")
+	// ErrUnsupported is returned for backends not available on this platform
+	ErrUnsupported = errors.New("trash backend not supported on this platform")
+)
+
+// Trash moves files to a trash and back
+type Trash interface {
+	// Name returns the backend name recorded with every trashed item
+	Name() string
+	// MoveToTrash moves the path to the trash and returns where it ended up
+	MoveToTrash(path string) (string, error)
+	// Restore moves a trashed item back to its original path
+	Restore(trashed, original string) error
+}
+
+// Returns the native trash of the platform, falling back to
+// the freedesktop.org trash where there is none
+func Default() Trash {
+	if native, err := Native(); err == nil {
+		return native
+	}
+	return NewFreedesktop()
+}
+
+// Returns the backend with the given name, or the default one for "".
+//
+// The in memory trash is not available by name, as its items do not
+// outlive the Memory value tests trash them with
+func ByName(name string) (Trash, error) {
+	switch name {
+	case "":
+		return Default(), nil
+	case NATIVE:
+		return Native()
+	case FREEDESKTOP:
+		return NewFreedesktop(), nil
+	}
+	return nil, fmt.Errorf("unknown trash backend %q, use %s or %s", name, NATIVE, FREEDESKTOP)
+}
+
+// Moves a trashed item back by renaming it, as trashes keep items on the original volume
+func renameBack(trashed, original string) error {
+	if _, err := os.Lstat(trashed); os.IsNotExist(err) {
+		return ErrNotInTrash
+	}
+	if _, err := os.Lstat(original); err == nil {
+		return ErrOccupied
+	}
+	if err := os.MkdirAll(filepath.Dir(original), 0755); err != nil {
+		return err
+	}
+	return os.Rename(trashed, original)
+}
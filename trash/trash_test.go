@@ -0,0 +1,176 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Creates a file with data, creating its parents
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFreedesktop(t *testing.T) {
+	dir := t.TempDir()
+	bin := Freedesktop{Home: filepath.Join(dir, "share", "Trash"), UID: os.Getuid()}
+
+	first := filepath.Join(dir, "a", "My Notes.txt")
+	second := filepath.Join(dir, "b", "My Notes.txt")
+	folder := filepath.Join(dir, "Library", "com.gemini.test")
+	writeFile(t, first, "first")
+	writeFile(t, second, "second")
+	writeFile(t, filepath.Join(folder, "data"), "data")
+
+	var trashed []string
+	for _, path := range []string{first, second, folder} {
+		location, err := bin.MoveToTrash(path)
+		if err != nil {
+			t.Fatalf("MoveToTrash(%s) failed: %v", path, err)
+		}
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone", path)
+		}
+		trashed = append(trashed, location)
+	}
+
+	files := filepath.Join(bin.Home, "files")
+	expected := []string{filepath.Join(files, "My Notes.txt"), filepath.Join(files, "My Notes.txt.2"), filepath.Join(files, "com.gemini.test")}
+	for i := range expected {
+		if trashed[i] != expected[i] {
+			t.Errorf("Trashed to %s, want %s", trashed[i], expected[i])
+		}
+	}
+
+	info, err := os.ReadFile(filepath.Join(bin.Home, "info", "My Notes.txt.2.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(info), "\n")
+	if lines[0] != INFO_HEADER || lines[1] != "Path="+filepath.ToSlash(filepath.Join(dir, "b", "My%20Notes.txt")) || !strings.HasPrefix(lines[2], "DeletionDate=") {
+		t.Errorf("Unexpected trash info:\n%s", info)
+	}
+
+	writeFile(t, first, "new")
+	if err := bin.Restore(trashed[0], first); !errors.Is(err, ErrOccupied) {
+		t.Errorf("Expected ErrOccupied restoring over a new file, got %v", err)
+	}
+	if err := bin.Restore(trashed[2], folder); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(folder, "data")); err != nil || string(data) != "data" {
+		t.Errorf("Expected restored folder contents, got %q, %v", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(bin.Home, "info", "com.gemini.test.trashinfo")); !os.IsNotExist(err) {
+		t.Errorf("Expected trash info to be removed on restore, got %v", err)
+	}
+	if err := bin.Restore(trashed[2], folder+".copy"); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Expected ErrNotInTrash restoring twice, got %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	dir := t.TempDir()
+	folder := filepath.Join(dir, "com.gemini.test")
+	writeFile(t, filepath.Join(folder, "nested", "data"), "data")
+	if err := os.Symlink("nested/data", filepath.Join(folder, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	bin := &Memory{Errs: map[string]error{filepath.Join(dir, "locked"): os.ErrPermission}}
+	if _, err := bin.MoveToTrash(filepath.Join(dir, "locked")); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Expected configured error, got %v", err)
+	}
+
+	trashed, err := bin.MoveToTrash(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(folder); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be gone", folder)
+	}
+	if bin.Originals[trashed] != folder {
+		t.Errorf("Expected %s to be recorded as the original of %s, got %v", folder, trashed, bin.Originals)
+	}
+
+	if err := bin.Restore(trashed, folder); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(folder, "link")); err != nil || string(data) != "data" {
+		t.Errorf("Expected restored symlink to resolve, got %q, %v", data, err)
+	}
+	if err := bin.Restore(trashed, folder); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Expected ErrNotInTrash restoring twice, got %v", err)
+	}
+
+	// Items trashed after a restore never take the place of another item
+	other := filepath.Join(dir, "copy", "com.gemini.test")
+	writeFile(t, other, "other")
+	first, err := bin.MoveToTrash(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := bin.MoveToTrash(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bin.Restore(first, folder); err != nil {
+		t.Fatal(err)
+	}
+	third, err := bin.MoveToTrash(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == second || bin.Originals[second] != other {
+		t.Errorf("Expected %s to keep %s, got %v", second, other, bin.Originals)
+	}
+}
+
+func TestByName(t *testing.T) {
+	if bin, err := ByName(FREEDESKTOP); err != nil || bin.Name() != FREEDESKTOP {
+		t.Errorf("ByName(%q) = %v, %v", FREEDESKTOP, bin, err)
+	}
+	if _, err := ByName("recycle-bin"); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+	if bin, err := ByName(""); err != nil || bin.Name() != Default().Name() {
+		t.Errorf("Expected the default backend for an empty name, got %v, %v", bin, err)
+	}
+}
+
+func TestDot(t *testing.T) {
+	dir := t.TempDir()
+	bin := Dot{Home: filepath.Join(dir, ".Trash"), UID: os.Getuid()}
+
+	first := filepath.Join(dir, "a", "report.txt")
+	second := filepath.Join(dir, "b", "report.txt")
+	writeFile(t, first, "first")
+	writeFile(t, second, "second")
+
+	var trashed []string
+	for _, path := range []string{first, second} {
+		location, err := bin.MoveToTrash(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trashed = append(trashed, location)
+	}
+	if want := filepath.Join(bin.Home, "report 2.txt"); trashed[1] != want {
+		t.Errorf("Trashed to %s, want %s", trashed[1], want)
+	}
+
+	if err := bin.Restore(trashed[1], second); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(second); err != nil || string(data) != "second" {
+		t.Errorf("Expected restored contents, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Returns the device the path lives on
+func deviceOf(path string) (uint64, bool) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
+
+// VolumeRoot returns the mount point of the volume holding path by
+// walking up the parents until the device changes
+func VolumeRoot(path string) string {
+	dev, ok := deviceOf(path)
+	if !ok {
+		return ""
+	}
+	root := path
+	for {
+		parent := filepath.Dir(root)
+		if parent == root {
+			return root
+		}
+		if parentDev, ok := deviceOf(parent); !ok || parentDev != dev {
+			return root
+		}
+		root = parent
+	}
+}